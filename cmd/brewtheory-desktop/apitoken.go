@@ -0,0 +1,111 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+
+	"github.com/urfave/cli/v2"
+)
+
+// apiTokenCommand manages the API tokens that authenticate JSON gateway
+// clients
+func apiTokenCommand(logOptions *logging.Options) *cli.Command {
+	return &cli.Command{
+		Name:  "apitoken",
+		Usage: "manage the API tokens used by the JSON gateway",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "create a named token & print it, it can't be shown again",
+				ArgsUsage: "<name>",
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.NArg() != 1 {
+						return cli.Exit("a token name is required", 1)
+					}
+					tokens, err := loadAPITokens(logOptions)
+					if err != nil {
+						return err
+					}
+					token, err := tokens.Create(cCtx.Args().First())
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					fmt.Println(token)
+					return nil
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "list the names of every token",
+				Action: func(cCtx *cli.Context) error {
+					tokens, err := loadAPITokens(logOptions)
+					if err != nil {
+						return err
+					}
+					list, err := tokens.List()
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					for _, token := range list {
+						fmt.Printf("%s\t%s\n", token.Name, token.Created.Local().Format("2006-01-02 15:04:05"))
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "revoke",
+				Usage:     "revoke a named token",
+				ArgsUsage: "<name>",
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.NArg() != 1 {
+						return cli.Exit("a token name is required", 1)
+					}
+					tokens, err := loadAPITokens(logOptions)
+					if err != nil {
+						return err
+					}
+					ok, err := tokens.Revoke(cCtx.Args().First())
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					if !ok {
+						return cli.Exit(fmt.Sprint("no api token named [", cCtx.Args().First(), "]"), 1)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func loadAPITokens(logOptions *logging.Options) (*rpc.APITokens, error) {
+	service, err := newService(logOptions)
+	if err != nil {
+		return nil, err
+	}
+	tokens, ok := rpc.NewAPITokens(service.Logger)
+	if !ok {
+		return nil, cli.Exit("unable to open config directory", 1)
+	}
+	return tokens, nil
+}
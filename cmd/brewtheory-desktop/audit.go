@@ -0,0 +1,102 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+
+	"github.com/urfave/cli/v2"
+)
+
+// auditCommand reads the audit journal of calls to mutating methods
+func auditCommand(logOptions *logging.Options) *cli.Command {
+	return &cli.Command{
+		Name:  "audit",
+		Usage: "read the audit journal of calls that changed state",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "export",
+				Usage: "print matching audit entries as json lines, oldest first",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "method",
+						Usage: "only entries for this method",
+					},
+					&cli.StringFlag{
+						Name:  "session",
+						Usage: "only entries made by this session id or api token name",
+					},
+					&cli.StringFlag{
+						Name:  "entity-type",
+						Usage: "only entries that changed an entity of this type",
+					},
+					&cli.StringFlag{
+						Name:  "entity-id",
+						Usage: "only entries that changed the entity with this id",
+					},
+					&cli.DurationFlag{
+						Name:  "since",
+						Usage: "only entries from within this long ago",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "only the newest entries",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					service, err := newService(logOptions)
+					if err != nil {
+						return err
+					}
+					journal, ok := rpc.NewAuditJournal(service.Logger)
+					if !ok {
+						return cli.Exit("unable to open config directory", 1)
+					}
+					filter := rpc.AuditFilter{
+						Method:     cCtx.String("method"),
+						Session:    cCtx.String("session"),
+						EntityType: cCtx.String("entity-type"),
+						EntityID:   cCtx.String("entity-id"),
+						Limit:      cCtx.Int("limit"),
+					}
+					if since := cCtx.Duration("since"); since > 0 {
+						filter.Since = time.Now().Add(-since)
+					}
+					entries, err := journal.Query(filter)
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					encoder := json.NewEncoder(os.Stdout)
+					for _, entry := range entries {
+						err = encoder.Encode(entry)
+						if err != nil {
+							return cli.Exit(err, 1)
+						}
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
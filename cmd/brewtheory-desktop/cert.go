@@ -0,0 +1,91 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+
+	"github.com/urfave/cli/v2"
+)
+
+// certCommand inspects & rotates the persisted TLS identity
+func certCommand(logOptions *logging.Options) *cli.Command {
+	return &cli.Command{
+		Name:  "cert",
+		Usage: "inspect or rotate the service TLS certificate",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "show",
+				Usage: "print the current certificate details",
+				Action: func(cCtx *cli.Context) error {
+					identity, err := loadIdentity(logOptions)
+					if err != nil {
+						return err
+					}
+					// only read the certificate, loading it would generate or
+					// rotate the key pair
+					leaf, err := identity.ReadCertificate()
+					if err != nil {
+						return cli.Exit(fmt.Sprint("unable to read certificate - ", err), 1)
+					}
+					printCertificate(identity.CertPath, leaf)
+					return nil
+				},
+			},
+			{
+				Name:  "rotate",
+				Usage: "replace the certificate & key with a new pair",
+				Action: func(cCtx *cli.Context) error {
+					identity, err := loadIdentity(logOptions)
+					if err != nil {
+						return err
+					}
+					if !identity.Rotate() {
+						return cli.Exit("unable to rotate certificate", 1)
+					}
+					printCertificate(identity.CertPath, identity.Leaf)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func loadIdentity(logOptions *logging.Options) (*rpc.Identity, error) {
+	service, err := newService(logOptions)
+	if err != nil {
+		return nil, err
+	}
+	identity, ok := rpc.NewIdentity(service.Logger)
+	if !ok {
+		return nil, cli.Exit("unable to open config directory", 1)
+	}
+	return identity, nil
+}
+
+func printCertificate(path string, leaf *x509.Certificate) {
+	fmt.Println("Path:       ", path)
+	fmt.Println("Subject:    ", leaf.Subject.String())
+	fmt.Println("Not Before: ", leaf.NotBefore)
+	fmt.Println("Not After:  ", leaf.NotAfter)
+	fmt.Println("SHA-256:    ", rpc.CertificateFingerprint(leaf))
+}
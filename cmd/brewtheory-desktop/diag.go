@@ -0,0 +1,74 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/farrcraft/brewtheory/internal/electron"
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
+
+	"github.com/urfave/cli/v2"
+)
+
+// diagCommand writes a diagnostic bundle to attach to support requests.
+// The service doesn't need to be running, but live stats are only included
+// in bundles created with the CreateDiagnostics RPC.
+func diagCommand(logOptions *logging.Options, configure func(*electron.Electron)) *cli.Command {
+	return &cli.Command{
+		Name:  "diag",
+		Usage: "write a diagnostic bundle of logs, settings, versions & health checks",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "output",
+				Value: ".",
+				Usage: "directory the bundle is written to",
+			},
+			&cli.BoolFlag{
+				Name:  "exclude-logs",
+				Usage: "leave the logs out of the bundle",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			service, err := newService(logOptions)
+			if err != nil {
+				return err
+			}
+			configure(service)
+			collector := service.Diagnostics()
+			collector.ExcludeLogs = cCtx.Bool("exclude-logs")
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			path, err := collector.WriteFile(ctx, cCtx.String("output"), nil)
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+}
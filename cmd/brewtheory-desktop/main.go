@@ -19,44 +19,184 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/farrcraft/brewtheory/internal/electron"
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
 
 	"github.com/urfave/cli/v2"
 )
 
 func main() {
-	var logLevel string
-	var logFile string
+	logOptions := logging.DefaultOptions
+	var subsystemLevels cli.StringSlice
 	var listenerAddress string
+	var bootstrapFd int
+	var lockPairing bool
+	var useTLS bool
+	var jsonGateway bool
+	var metricsAddress string
+	limits := rpc.DefaultLimits
+
+	// configure applies the service flags, other than the bootstrap secret
+	configure := func(service *electron.Electron) {
+		service.Address = listenerAddress
+		service.LockPairing = lockPairing
+		service.DisableTLS = !useTLS
+		service.Limits = limits
+		service.JSONGateway = jsonGateway
+		service.MetricsAddress = metricsAddress
+	}
 
 	app := &cli.App{
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "logfile",
-				Value:       "brewtheory.log",
-				Usage:       "log file name",
-				Destination: &logFile,
+				Usage:       "log file path, defaults to logs/" + electron.DefaultLogFile + " in the config directory",
+				Destination: &logOptions.File,
 			},
 			&cli.StringFlag{
 				Name:        "loglevel",
-				Value:       "DEBUG",
+				Value:       logOptions.Level,
 				Usage:       "log level",
-				Destination: &logLevel,
+				Destination: &logOptions.Level,
+			},
+			&cli.StringSliceFlag{
+				Name:        "subsystem-loglevel",
+				Usage:       "log level of a subsystem (rpc, jobs, diag, db, sensors or calc) given as subsystem=level, may be repeated",
+				Destination: &subsystemLevels,
+			},
+			&cli.Int64Flag{
+				Name:        "log-max-size",
+				Value:       logOptions.MaxSize,
+				Usage:       "bytes written to the log file before it is rotated, 0 to disable",
+				Destination: &logOptions.MaxSize,
+			},
+			&cli.DurationFlag{
+				Name:        "log-max-age",
+				Value:       logOptions.MaxAge,
+				Usage:       "how long the log file is written to before it is rotated, 0 to disable",
+				Destination: &logOptions.MaxAge,
+			},
+			&cli.IntFlag{
+				Name:        "log-max-backups",
+				Value:       logOptions.MaxBackups,
+				Usage:       "rotated log files to keep, 0 to keep them all",
+				Destination: &logOptions.MaxBackups,
 			},
 			&cli.StringFlag{
 				Name:        "listen",
 				Value:       "localhost:53017",
-				Usage:       "service listener address",
+				Usage:       "service listener address, either host:port (port 0 picks a free port), unix:///path/to/socket or unix:@abstract-name",
 				Destination: &listenerAddress,
 			},
+			&cli.IntFlag{
+				Name:        "bootstrap-fd",
+				Value:       -1,
+				Usage:       "read the bootstrap secret from this file descriptor (0 for stdin) instead of " + electron.BootstrapEnv,
+				Destination: &bootstrapFd,
+			},
+			&cli.BoolFlag{
+				Name:        "lock-pairing",
+				Usage:       "refuse key exchanges after the first one succeeds",
+				Destination: &lockPairing,
+			},
+			&cli.BoolFlag{
+				Name:        "tls",
+				Value:       true,
+				Usage:       "serve over TLS, may only be disabled when listening on a unix socket",
+				Destination: &useTLS,
+			},
+			&cli.BoolFlag{
+				Name:        "json-gateway",
+				Usage:       "serve every handler as JSON under /json/<Method> to clients holding an API token",
+				Destination: &jsonGateway,
+			},
+			&cli.StringFlag{
+				Name:        "metrics",
+				Usage:       "serve prometheus metrics on a loopback address, such as 127.0.0.1:9464",
+				Destination: &metricsAddress,
+			},
+			&cli.Int64Flag{
+				Name:        "max-body-size",
+				Value:       limits.MaxBodySize,
+				Usage:       "largest request body in bytes, both as sent & decompressed",
+				Destination: &limits.MaxBodySize,
+			},
+			&cli.DurationFlag{
+				Name:        "read-header-timeout",
+				Value:       limits.ReadHeaderTimeout,
+				Usage:       "how long a client may take to send its request headers",
+				Destination: &limits.ReadHeaderTimeout,
+			},
+			&cli.DurationFlag{
+				Name:        "read-timeout",
+				Value:       limits.ReadTimeout,
+				Usage:       "how long a client may take to send its whole request",
+				Destination: &limits.ReadTimeout,
+			},
+			&cli.DurationFlag{
+				Name:        "idle-timeout",
+				Value:       limits.IdleTimeout,
+				Usage:       "how long an unused keep-alive connection is kept open",
+				Destination: &limits.IdleTimeout,
+			},
+			&cli.Float64Flag{
+				Name:        "rate-limit",
+				Value:       limits.Token.Rate,
+				Usage:       "calls per second allowed for each session, 0 to disable",
+				Destination: &limits.Token.Rate,
+			},
+			&cli.Float64Flag{
+				Name:        "preauth-rate-limit",
+				Value:       limits.PreAuth.Rate,
+				Usage:       "requests per second allowed from each host without a session, 0 to disable",
+				Destination: &limits.PreAuth.Rate,
+			},
+			&cli.Float64Flag{
+				Name:        "kex-rate-limit",
+				Value:       limits.KeyExchange.Rate,
+				Usage:       "key exchanges per second allowed from each host or session, 0 to disable",
+				Destination: &limits.KeyExchange.Rate,
+			},
+		},
+		Commands: []*cli.Command{
+			certCommand(&logOptions),
+			methodsCommand(&logOptions),
+			apiTokenCommand(&logOptions),
+			auditCommand(&logOptions),
+			diagCommand(&logOptions, configure),
+		},
+		Before: func(cCtx *cli.Context) error {
+			var err error
+			logOptions.Levels, err = logging.ParseLevels(subsystemLevels.Value())
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			return nil
 		},
 		Action: func(cCtx *cli.Context) error {
-			service := electron.NewElectron(logLevel, logFile)
+			service, err := newService(&logOptions)
+			if err != nil {
+				return err
+			}
+			secret, err := electron.ReadBootstrapSecret(bootstrapFd)
+			if err != nil {
+				service.Logger.Error("Error reading bootstrap secret - ", err)
+				fmt.Println(rpc.Failure(codes.New(codes.ScopeGeneral, codes.ErrorBootstrapSecret)))
+				return cli.Exit(err, 1)
+			}
+			service.BootstrapSecret = secret
+			configure(service)
 			service.Logger.Debug("Starting Service...")
-			service.Run(listenerAddress)
+			err = service.Run(listenerAddress)
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
 			return nil
 		},
 	}
@@ -65,3 +205,12 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// newService creates the backend service, logging as configured
+func newService(logOptions *logging.Options) (*electron.Electron, error) {
+	service, err := electron.NewElectron(*logOptions)
+	if err != nil {
+		return nil, cli.Exit(err, 1)
+	}
+	return service, nil
+}
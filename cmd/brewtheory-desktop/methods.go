@@ -0,0 +1,55 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/farrcraft/brewtheory/internal/electron/handler"
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+
+	"github.com/urfave/cli/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// methodsCommand prints the catalog of RPC methods & the schema of their
+// messages.  The output is the JSON form of a ListMethodsResponse, the same
+// catalog the ListMethods RPC returns.
+func methodsCommand(logOptions *logging.Options) *cli.Command {
+	return &cli.Command{
+		Name:  "methods",
+		Usage: "print the RPC method catalog as JSON",
+		Action: func(cCtx *cli.Context) error {
+			service, err := newService(logOptions)
+			if err != nil {
+				return err
+			}
+			server := rpc.NewServer(service.Logger, nil, nil)
+			handler.Register(server)
+
+			output, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(server.Catalog())
+			if err != nil {
+				return cli.Exit(fmt.Sprint("unable to encode method catalog - ", err), 1)
+			}
+			fmt.Println(string(output))
+			return nil
+		},
+	}
+}
@@ -18,6 +18,7 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 import ApiInterface from '../interfaces/api/Api';
 import RegistrarInterface from '../interfaces/api/Registrar';
+import Cancel from './endpoints/Cancel';
 import Kex from './endpoints/Kex';
 
 /**
@@ -40,6 +41,7 @@ class Registrar implements RegistrarInterface {
    */
   register(): void {
     this.api.registerProvider(new Kex());
+    this.api.registerProvider(new Cancel());
   }
 }
 
@@ -0,0 +1,69 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+import Endpoint from '../Endpoint';
+import EndpointInterface from '../../interfaces/api/Endpoint';
+import InternalError from '../../core/InternalError';
+import * as commonProto from '../../proto/common';
+import * as cancelProto from '../../proto/cancel';
+
+/**
+ * Cancels calls that are still in flight, e.g. a long running import the
+ * user gave up on
+ */
+class Cancel extends Endpoint implements EndpointInterface {
+  /**
+   *
+   */
+  constructor() {
+    super();
+    this.setName('cancel');
+  }
+
+  /**
+   * The sequence of a call is the client's send counter right after the call
+   * was made.
+   *
+   * @param sequence The sequence number the call was sent with
+   * @returns whether the call was still in flight
+   */
+  async cancel(sequence: number): Promise<boolean> {
+    if (this.rpc === null) {
+      throw new InternalError('Service Error', 'RPC Unavailable');
+    }
+    const message = new cancelProto.brewtheory.CancelRequest();
+    const messageHeader = new commonProto.brewtheory.RequestHeader();
+    messageHeader.method = 'cancel';
+    message.header = messageHeader;
+    message.sequence = sequence;
+
+    const payload = message.serializeBinary();
+    const responseBody = await this.rpc.request('Cancel', payload);
+
+    const responseMessage =
+      cancelProto.brewtheory.CancelResponse.deserializeBinary(
+        Uint8Array.from(Buffer.from(responseBody, 'hex'))
+      );
+    if (responseMessage.has_header && responseMessage.header.code !== 0) {
+      throw new InternalError('Service Error', responseMessage.header.status);
+    }
+    return responseMessage.cancelled;
+  }
+}
+
+export default Cancel;
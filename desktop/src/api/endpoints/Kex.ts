@@ -16,12 +16,28 @@ You should have received a copy of the GNU General Public License
 along with this program.  If not, see <http://www.gnu.org/licenses/>.
 */
 
+import { createHmac } from 'crypto';
 import Endpoint from '../Endpoint';
 import EndpointInterface from '../../interfaces/api/Endpoint';
 import InternalError from '../../core/InternalError';
 import * as commonProto from '../../proto/common';
 import * as kexProto from '../../proto/kex';
 
+/**
+ * The wire protocol version this client speaks
+ */
+export const PROTOCOL_VERSION = 1;
+
+/**
+ * The oldest backend protocol version this client can talk to
+ */
+export const MIN_PROTOCOL_VERSION = 1;
+
+/**
+ * The optional backend features this client supports
+ */
+export const CAPABILITIES: Array<string> = [];
+
 /**
  *
  */
@@ -36,8 +52,13 @@ class Kex extends Endpoint implements EndpointInterface {
 
   /**
    *
+   * @param bootstrapSecret The hex encoded secret shared with the backend at launch
+   * @param appVersion The version of the frontend
    */
-  async keyExchange(): Promise<void> {
+  async keyExchange(
+    bootstrapSecret: string | null,
+    appVersion: string
+  ): Promise<void> {
     if (this.rpc === null) {
       throw new InternalError('Service Error', 'RPC Unavailable');
     }
@@ -46,6 +67,18 @@ class Kex extends Endpoint implements EndpointInterface {
     messageHeader.method = 'keyExchange';
     message.header = messageHeader;
     message.publicKey = this.rpc.client.signPublicKey;
+    message.protocolVersion = PROTOCOL_VERSION;
+    message.appVersion = appVersion;
+    message.capabilities = CAPABILITIES;
+    if (bootstrapSecret !== null) {
+      // prove that we're the process that launched the backend
+      message.bootstrapProof = createHmac(
+        'sha256',
+        Buffer.from(bootstrapSecret, 'hex')
+      )
+        .update(message.publicKey)
+        .digest();
+    }
 
     const payload = message.serializeBinary();
     const responseBody = await this.rpc.request('KeyExchange', payload);
@@ -55,12 +88,28 @@ class Kex extends Endpoint implements EndpointInterface {
         Uint8Array.from(Buffer.from(responseBody, 'hex'))
       );
 
+    // refused exchanges aren't signed, so report them before verification
+    if (responseMessage.has_header && responseMessage.header.code !== 0) {
+      throw new InternalError('Service Error', responseMessage.header.status);
+    }
+
     this.rpc.client.verifyPublicKey = responseMessage.publicKey;
     this.rpc.client.clientToken = responseMessage.token;
 
     // responses would normally be verified directly by the rpc call, but it has to
     // be deferred in the case of key exchange - this throws on verification failure
     await this.rpc.client.verifyLastResponse();
+
+    // the backend refuses clients that are too old, but we have to check
+    // whether it is too old for us
+    const protocolVersion = responseMessage.protocolVersion || 1;
+    if (protocolVersion < MIN_PROTOCOL_VERSION) {
+      throw new InternalError(
+        'Service Error',
+        `Backend version ${responseMessage.appVersion} speaks unsupported protocol version ${protocolVersion}`
+      );
+    }
+    this.rpc.client.capabilities = responseMessage.capabilities;
   }
 }
 
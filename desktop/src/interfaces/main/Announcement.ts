@@ -0,0 +1,60 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+/**
+ * How the backend guards pairing
+ */
+export interface BootstrapInfo {
+  required: boolean;
+  lockPairing: boolean;
+}
+
+/**
+ * Written to stdout by the backend once it is listening
+ */
+export interface ReadyAnnouncement {
+  event: 'ready';
+  /**
+   * The bound host:port, or the unix socket address
+   */
+  address: string;
+  tls: boolean;
+  fingerprint?: string;
+  pid: number;
+  protocolVersion: number;
+  minProtocolVersion: number;
+  appVersion?: string;
+  bootstrap: BootstrapInfo;
+}
+
+/**
+ * Written to stdout by the backend when it fails to start
+ */
+export interface FailureAnnouncement {
+  event: 'error';
+  pid: number;
+  error: {
+    scope: number;
+    code: number;
+    message: string;
+  };
+}
+
+type Announcement = ReadyAnnouncement | FailureAnnouncement;
+
+export default Announcement;
@@ -58,6 +58,11 @@ interface Client {
    */
   clientToken: string;
 
+  /**
+   * The optional features negotiated with the backend during key exchange
+   */
+  capabilities: Array<string>;
+
   /**
    *
    */
@@ -72,21 +77,43 @@ interface Client {
    *
    * @param method
    * @param payload
+   * @param timeout Milliseconds the backend may spend on the call
    */
-  request(method: string, payload: Uint8Array | null): Promise<boolean>;
+  request(
+    method: string,
+    payload: Uint8Array | null,
+    timeout?: number
+  ): Promise<boolean>;
 
   /**
+   * Build the canonical envelope that is signed for a message
    *
+   * @param kind
+   * @param method
+   * @param sequence
+   * @param timestamp
    * @param payload
    */
-  createSignature(payload: string): Promise<string>;
+  createEnvelope(
+    kind: string,
+    method: string,
+    sequence: number,
+    timestamp: number,
+    payload: Uint8Array
+  ): Promise<Uint8Array>;
+
+  /**
+   *
+   * @param envelope
+   */
+  createSignature(envelope: Uint8Array): Promise<string>;
 
   /**
    *
    * @param signature
    * @param payload
    */
-  verifySignature(signature: string, payload: string): Promise<boolean>;
+  verifySignature(signature: string, envelope: Uint8Array): Promise<boolean>;
 
   /**
    *
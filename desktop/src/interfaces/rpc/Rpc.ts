@@ -37,8 +37,13 @@ interface Rpc {
    *
    * @param method
    * @param payload
+   * @param timeout Milliseconds the backend may spend on the call
    */
-  request(method: string, payload: Uint8Array): Promise<string>;
+  request(
+    method: string,
+    payload: Uint8Array,
+    timeout?: number
+  ): Promise<string>;
 }
 
 export default Rpc;
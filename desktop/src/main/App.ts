@@ -191,7 +191,7 @@ class App {
     // [FIXME] - should use constant of some kind of endpoint name here?
     const kex = <Kex>this.api.getEndpoint('kex');
     try {
-      await kex.keyExchange();
+      await kex.keyExchange(this.backend.bootstrapSecret, app.getVersion());
     } catch (err) {
       this.logger.error(`Key exchange failed ${err}`);
       // [FIXME] - shutdown
@@ -17,9 +17,17 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 */
 
 import childProcess from 'child_process';
+import { randomBytes } from 'crypto';
 
 import Logger from '../core/Logger';
+import Announcement, {
+  ReadyAnnouncement,
+} from '../interfaces/main/Announcement';
 import BackendInterface from '../interfaces/main/Backend';
+import {
+  setEndpointFingerprint,
+  setEndpointPort,
+} from '../rpc/Endpoint';
 
 type BackendReadyCallback = () => void;
 
@@ -42,6 +50,23 @@ export default class Backend implements BackendInterface {
    */
   readyListener: BackendReadyCallback;
 
+  /**
+   * SHA-256 fingerprint of the certificate the backend is serving.
+   * RPC connections are pinned to it.
+   */
+  certificateFingerprint: string | null = null;
+
+  /**
+   * What the backend announced once it was listening
+   */
+  announcement: ReadyAnnouncement | null = null;
+
+  /**
+   * Hex encoded secret handed to the backend at launch.
+   * Key exchange must prove knowledge of it, so only we can pair.
+   */
+  bootstrapSecret: string | null = null;
+
   /**
    *
    */
@@ -55,7 +80,21 @@ export default class Backend implements BackendInterface {
    */
   start(): void {
     this.logger.debug('Spawning backend process...');
-    this.process = childProcess.spawn('./src/resources/backend');
+    this.bootstrapSecret = randomBytes(32).toString('hex');
+    // the secret is passed on stdin so it isn't visible in the process list
+    // or environment, & pairing is locked once we've completed a key exchange.
+    // The backend picks a free port & tells us which one it bound.
+    this.process = childProcess.spawn('./src/resources/backend', [
+      '--bootstrap-fd',
+      '0',
+      '--lock-pairing',
+      '--listen',
+      'localhost:0',
+    ]);
+    if (this.process.stdin !== null) {
+      this.process.stdin.write(`${this.bootstrapSecret}\n`);
+      this.process.stdin.end();
+    }
     if (this.process.stdout !== null) {
       this.process.stdout.on('data', (data) => this.onStdout(data));
     }
@@ -70,17 +109,70 @@ export default class Backend implements BackendInterface {
    * @param data
    */
   onStdout(data: any): void {
-    const out = data.toString();
-    // This tells us that the backend is starting the server to listen for requests
-    // There may still be some latency before the server is actually ready to
-    // service requests.  We'll want to make actual RPC calls to the SERVICE-READY
-    // endpoint after this to guarantee the backend is fully operational.
-    if (out === 'SERVICE_READY\n') {
-      this.logger.debug('Backend service is ready');
-      this.readyListener();
-    } else {
-      this.logger.debug(out);
+    // a single chunk may carry several status lines
+    const lines: Array<string> = data.toString().split('\n');
+    lines.forEach((line) => {
+      if (line === '') {
+        return;
+      }
+      const announcement = Backend.parseAnnouncement(line);
+      if (announcement === null) {
+        this.logger.debug(line);
+      } else if (announcement.event === 'ready') {
+        this.onReady(announcement);
+      } else {
+        this.logger.error(
+          `Backend failed to start [${announcement.error.scope}:${announcement.error.code}] ${announcement.error.message}`
+        );
+      }
+    });
+  }
+
+  /**
+   * Startup announcements are single JSON lines, anything else is logged
+   *
+   * @param line
+   */
+  static parseAnnouncement(line: string): Announcement | null {
+    if (!line.startsWith('{')) {
+      return null;
+    }
+    try {
+      const announcement = JSON.parse(line);
+      if (announcement.event === 'ready' || announcement.event === 'error') {
+        return announcement;
+      }
+    } catch (err) {
+      // not an announcement
+    }
+    return null;
+  }
+
+  /**
+   * This tells us that the backend is starting the server to listen for requests
+   * There may still be some latency before the server is actually ready to
+   * service requests.  We'll want to make actual RPC calls to the SERVICE-READY
+   * endpoint after this to guarantee the backend is fully operational.
+   *
+   * @param announcement
+   */
+  onReady(announcement: ReadyAnnouncement): void {
+    this.announcement = announcement;
+    this.certificateFingerprint = announcement.fingerprint ?? null;
+    const port = Number(
+      announcement.address.substring(announcement.address.lastIndexOf(':') + 1)
+    );
+    setEndpointPort(port);
+    if (this.certificateFingerprint !== null) {
+      setEndpointFingerprint(this.certificateFingerprint);
     }
+    this.logger.debug(
+      `Backend service is ready on ${announcement.address} pid ${announcement.pid}`
+    );
+    this.logger.debug(
+      `Backend certificate fingerprint ${this.certificateFingerprint}`
+    );
+    this.readyListener();
   }
 
   /**
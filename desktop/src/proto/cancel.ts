@@ -0,0 +1,195 @@
+/**
+ * Generated by the protoc-gen-ts.  DO NOT EDIT!
+ * compiler version: 3.21.5
+ * source: cancel.proto
+ * git: https://github.com/thesayyn/protoc-gen-ts */
+import * as dependency_1 from "./common";
+import * as pb_1 from "google-protobuf";
+export namespace brewtheory {
+    export class CancelRequest extends pb_1.Message {
+        #one_of_decls: number[][] = [];
+        constructor(data?: any[] | {
+            header?: dependency_1.brewtheory.RequestHeader;
+            sequence?: number;
+        }) {
+            super();
+            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [], this.#one_of_decls);
+            if (!Array.isArray(data) && typeof data == "object") {
+                if ("header" in data && data.header != undefined) {
+                    this.header = data.header;
+                }
+                if ("sequence" in data && data.sequence != undefined) {
+                    this.sequence = data.sequence;
+                }
+            }
+        }
+        get header() {
+            return pb_1.Message.getWrapperField(this, dependency_1.brewtheory.RequestHeader, 1) as dependency_1.brewtheory.RequestHeader;
+        }
+        set header(value: dependency_1.brewtheory.RequestHeader) {
+            pb_1.Message.setWrapperField(this, 1, value);
+        }
+        get has_header() {
+            return pb_1.Message.getField(this, 1) != null;
+        }
+        get sequence() {
+            return pb_1.Message.getFieldWithDefault(this, 2, 0) as number;
+        }
+        set sequence(value: number) {
+            pb_1.Message.setField(this, 2, value);
+        }
+        static fromObject(data: {
+            header?: ReturnType<typeof dependency_1.brewtheory.RequestHeader.prototype.toObject>;
+            sequence?: number;
+        }): CancelRequest {
+            const message = new CancelRequest({});
+            if (data.header != null) {
+                message.header = dependency_1.brewtheory.RequestHeader.fromObject(data.header);
+            }
+            if (data.sequence != null) {
+                message.sequence = data.sequence;
+            }
+            return message;
+        }
+        toObject() {
+            const data: {
+                header?: ReturnType<typeof dependency_1.brewtheory.RequestHeader.prototype.toObject>;
+                sequence?: number;
+            } = {};
+            if (this.header != null) {
+                data.header = this.header.toObject();
+            }
+            if (this.sequence != null) {
+                data.sequence = this.sequence;
+            }
+            return data;
+        }
+        serialize(): Uint8Array;
+        serialize(w: pb_1.BinaryWriter): void;
+        serialize(w?: pb_1.BinaryWriter): Uint8Array | void {
+            const writer = w || new pb_1.BinaryWriter();
+            if (this.has_header)
+                writer.writeMessage(1, this.header, () => this.header.serialize(writer));
+            if (this.sequence != 0)
+                writer.writeInt32(2, this.sequence);
+            if (!w)
+                return writer.getResultBuffer();
+        }
+        static deserialize(bytes: Uint8Array | pb_1.BinaryReader): CancelRequest {
+            const reader = bytes instanceof pb_1.BinaryReader ? bytes : new pb_1.BinaryReader(bytes), message = new CancelRequest();
+            while (reader.nextField()) {
+                if (reader.isEndGroup())
+                    break;
+                switch (reader.getFieldNumber()) {
+                    case 1:
+                        reader.readMessage(message.header, () => message.header = dependency_1.brewtheory.RequestHeader.deserialize(reader));
+                        break;
+                    case 2:
+                        message.sequence = reader.readInt32();
+                        break;
+                    default: reader.skipField();
+                }
+            }
+            return message;
+        }
+        serializeBinary(): Uint8Array {
+            return this.serialize();
+        }
+        static deserializeBinary(bytes: Uint8Array): CancelRequest {
+            return CancelRequest.deserialize(bytes);
+        }
+    }
+    export class CancelResponse extends pb_1.Message {
+        #one_of_decls: number[][] = [];
+        constructor(data?: any[] | {
+            header?: dependency_1.brewtheory.ResponseHeader;
+            cancelled?: boolean;
+        }) {
+            super();
+            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [], this.#one_of_decls);
+            if (!Array.isArray(data) && typeof data == "object") {
+                if ("header" in data && data.header != undefined) {
+                    this.header = data.header;
+                }
+                if ("cancelled" in data && data.cancelled != undefined) {
+                    this.cancelled = data.cancelled;
+                }
+            }
+        }
+        get header() {
+            return pb_1.Message.getWrapperField(this, dependency_1.brewtheory.ResponseHeader, 1) as dependency_1.brewtheory.ResponseHeader;
+        }
+        set header(value: dependency_1.brewtheory.ResponseHeader) {
+            pb_1.Message.setWrapperField(this, 1, value);
+        }
+        get has_header() {
+            return pb_1.Message.getField(this, 1) != null;
+        }
+        get cancelled() {
+            return pb_1.Message.getFieldWithDefault(this, 2, false) as boolean;
+        }
+        set cancelled(value: boolean) {
+            pb_1.Message.setField(this, 2, value);
+        }
+        static fromObject(data: {
+            header?: ReturnType<typeof dependency_1.brewtheory.ResponseHeader.prototype.toObject>;
+            cancelled?: boolean;
+        }): CancelResponse {
+            const message = new CancelResponse({});
+            if (data.header != null) {
+                message.header = dependency_1.brewtheory.ResponseHeader.fromObject(data.header);
+            }
+            if (data.cancelled != null) {
+                message.cancelled = data.cancelled;
+            }
+            return message;
+        }
+        toObject() {
+            const data: {
+                header?: ReturnType<typeof dependency_1.brewtheory.ResponseHeader.prototype.toObject>;
+                cancelled?: boolean;
+            } = {};
+            if (this.header != null) {
+                data.header = this.header.toObject();
+            }
+            if (this.cancelled != null) {
+                data.cancelled = this.cancelled;
+            }
+            return data;
+        }
+        serialize(): Uint8Array;
+        serialize(w: pb_1.BinaryWriter): void;
+        serialize(w?: pb_1.BinaryWriter): Uint8Array | void {
+            const writer = w || new pb_1.BinaryWriter();
+            if (this.has_header)
+                writer.writeMessage(1, this.header, () => this.header.serialize(writer));
+            if (this.cancelled != false)
+                writer.writeBool(2, this.cancelled);
+            if (!w)
+                return writer.getResultBuffer();
+        }
+        static deserialize(bytes: Uint8Array | pb_1.BinaryReader): CancelResponse {
+            const reader = bytes instanceof pb_1.BinaryReader ? bytes : new pb_1.BinaryReader(bytes), message = new CancelResponse();
+            while (reader.nextField()) {
+                if (reader.isEndGroup())
+                    break;
+                switch (reader.getFieldNumber()) {
+                    case 1:
+                        reader.readMessage(message.header, () => message.header = dependency_1.brewtheory.ResponseHeader.deserialize(reader));
+                        break;
+                    case 2:
+                        message.cancelled = reader.readBool();
+                        break;
+                    default: reader.skipField();
+                }
+            }
+            return message;
+        }
+        serializeBinary(): Uint8Array {
+            return this.serialize();
+        }
+        static deserializeBinary(bytes: Uint8Array): CancelResponse {
+            return CancelResponse.deserialize(bytes);
+        }
+    }
+}
@@ -118,15 +118,223 @@ export namespace brewtheory {
             return RequestHeader.deserialize(bytes);
         }
     }
+    export class FieldViolation extends pb_1.Message {
+        #one_of_decls: number[][] = [];
+        constructor(data?: any[] | {
+            field?: string;
+            key?: string;
+            description?: string;
+        }) {
+            super();
+            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [], this.#one_of_decls);
+            if (!Array.isArray(data) && typeof data == "object") {
+                if ("field" in data && data.field != undefined) {
+                    this.field = data.field;
+                }
+                if ("key" in data && data.key != undefined) {
+                    this.key = data.key;
+                }
+                if ("description" in data && data.description != undefined) {
+                    this.description = data.description;
+                }
+            }
+        }
+        get field() {
+            return pb_1.Message.getFieldWithDefault(this, 1, "") as string;
+        }
+        set field(value: string) {
+            pb_1.Message.setField(this, 1, value);
+        }
+        get key() {
+            return pb_1.Message.getFieldWithDefault(this, 2, "") as string;
+        }
+        set key(value: string) {
+            pb_1.Message.setField(this, 2, value);
+        }
+        get description() {
+            return pb_1.Message.getFieldWithDefault(this, 3, "") as string;
+        }
+        set description(value: string) {
+            pb_1.Message.setField(this, 3, value);
+        }
+        static fromObject(data: {
+            field?: string;
+            key?: string;
+            description?: string;
+        }): FieldViolation {
+            const message = new FieldViolation({});
+            if (data.field != null) {
+                message.field = data.field;
+            }
+            if (data.key != null) {
+                message.key = data.key;
+            }
+            if (data.description != null) {
+                message.description = data.description;
+            }
+            return message;
+        }
+        toObject() {
+            const data: {
+                field?: string;
+                key?: string;
+                description?: string;
+            } = {};
+            if (this.field != null) {
+                data.field = this.field;
+            }
+            if (this.key != null) {
+                data.key = this.key;
+            }
+            if (this.description != null) {
+                data.description = this.description;
+            }
+            return data;
+        }
+        serialize(): Uint8Array;
+        serialize(w: pb_1.BinaryWriter): void;
+        serialize(w?: pb_1.BinaryWriter): Uint8Array | void {
+            const writer = w || new pb_1.BinaryWriter();
+            if (this.field.length)
+                writer.writeString(1, this.field);
+            if (this.key.length)
+                writer.writeString(2, this.key);
+            if (this.description.length)
+                writer.writeString(3, this.description);
+            if (!w)
+                return writer.getResultBuffer();
+        }
+        static deserialize(bytes: Uint8Array | pb_1.BinaryReader): FieldViolation {
+            const reader = bytes instanceof pb_1.BinaryReader ? bytes : new pb_1.BinaryReader(bytes), message = new FieldViolation();
+            while (reader.nextField()) {
+                if (reader.isEndGroup())
+                    break;
+                switch (reader.getFieldNumber()) {
+                    case 1:
+                        message.field = reader.readString();
+                        break;
+                    case 2:
+                        message.key = reader.readString();
+                        break;
+                    case 3:
+                        message.description = reader.readString();
+                        break;
+                    default: reader.skipField();
+                }
+            }
+            return message;
+        }
+        serializeBinary(): Uint8Array {
+            return this.serialize();
+        }
+        static deserializeBinary(bytes: Uint8Array): FieldViolation {
+            return FieldViolation.deserialize(bytes);
+        }
+    }
+    export class EntityReference extends pb_1.Message {
+        #one_of_decls: number[][] = [];
+        constructor(data?: any[] | {
+            type?: string;
+            id?: string;
+        }) {
+            super();
+            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [], this.#one_of_decls);
+            if (!Array.isArray(data) && typeof data == "object") {
+                if ("type" in data && data.type != undefined) {
+                    this.type = data.type;
+                }
+                if ("id" in data && data.id != undefined) {
+                    this.id = data.id;
+                }
+            }
+        }
+        get type() {
+            return pb_1.Message.getFieldWithDefault(this, 1, "") as string;
+        }
+        set type(value: string) {
+            pb_1.Message.setField(this, 1, value);
+        }
+        get id() {
+            return pb_1.Message.getFieldWithDefault(this, 2, "") as string;
+        }
+        set id(value: string) {
+            pb_1.Message.setField(this, 2, value);
+        }
+        static fromObject(data: {
+            type?: string;
+            id?: string;
+        }): EntityReference {
+            const message = new EntityReference({});
+            if (data.type != null) {
+                message.type = data.type;
+            }
+            if (data.id != null) {
+                message.id = data.id;
+            }
+            return message;
+        }
+        toObject() {
+            const data: {
+                type?: string;
+                id?: string;
+            } = {};
+            if (this.type != null) {
+                data.type = this.type;
+            }
+            if (this.id != null) {
+                data.id = this.id;
+            }
+            return data;
+        }
+        serialize(): Uint8Array;
+        serialize(w: pb_1.BinaryWriter): void;
+        serialize(w?: pb_1.BinaryWriter): Uint8Array | void {
+            const writer = w || new pb_1.BinaryWriter();
+            if (this.type.length)
+                writer.writeString(1, this.type);
+            if (this.id.length)
+                writer.writeString(2, this.id);
+            if (!w)
+                return writer.getResultBuffer();
+        }
+        static deserialize(bytes: Uint8Array | pb_1.BinaryReader): EntityReference {
+            const reader = bytes instanceof pb_1.BinaryReader ? bytes : new pb_1.BinaryReader(bytes), message = new EntityReference();
+            while (reader.nextField()) {
+                if (reader.isEndGroup())
+                    break;
+                switch (reader.getFieldNumber()) {
+                    case 1:
+                        message.type = reader.readString();
+                        break;
+                    case 2:
+                        message.id = reader.readString();
+                        break;
+                    default: reader.skipField();
+                }
+            }
+            return message;
+        }
+        serializeBinary(): Uint8Array {
+            return this.serialize();
+        }
+        static deserializeBinary(bytes: Uint8Array): EntityReference {
+            return EntityReference.deserialize(bytes);
+        }
+    }
     export class ResponseHeader extends pb_1.Message {
         #one_of_decls: number[][] = [];
         constructor(data?: any[] | {
             status?: string;
             code?: number;
             scope?: number;
+            type?: number;
+            key?: string;
+            violations?: FieldViolation[];
+            entities?: EntityReference[];
+            retryAfterMs?: number;
         }) {
             super();
-            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [], this.#one_of_decls);
+            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [6, 7], this.#one_of_decls);
             if (!Array.isArray(data) && typeof data == "object") {
                 if ("status" in data && data.status != undefined) {
                     this.status = data.status;
@@ -137,6 +345,21 @@ export namespace brewtheory {
                 if ("scope" in data && data.scope != undefined) {
                     this.scope = data.scope;
                 }
+                if ("type" in data && data.type != undefined) {
+                    this.type = data.type;
+                }
+                if ("key" in data && data.key != undefined) {
+                    this.key = data.key;
+                }
+                if ("violations" in data && data.violations != undefined) {
+                    this.violations = data.violations;
+                }
+                if ("entities" in data && data.entities != undefined) {
+                    this.entities = data.entities;
+                }
+                if ("retryAfterMs" in data && data.retryAfterMs != undefined) {
+                    this.retryAfterMs = data.retryAfterMs;
+                }
             }
         }
         get status() {
@@ -157,10 +380,45 @@ export namespace brewtheory {
         set scope(value: number) {
             pb_1.Message.setField(this, 3, value);
         }
+        get type() {
+            return pb_1.Message.getFieldWithDefault(this, 4, 0) as number;
+        }
+        set type(value: number) {
+            pb_1.Message.setField(this, 4, value);
+        }
+        get key() {
+            return pb_1.Message.getFieldWithDefault(this, 5, "") as string;
+        }
+        set key(value: string) {
+            pb_1.Message.setField(this, 5, value);
+        }
+        get violations() {
+            return pb_1.Message.getRepeatedWrapperField(this, FieldViolation, 6) as FieldViolation[];
+        }
+        set violations(value: FieldViolation[]) {
+            pb_1.Message.setRepeatedWrapperField(this, 6, value);
+        }
+        get entities() {
+            return pb_1.Message.getRepeatedWrapperField(this, EntityReference, 7) as EntityReference[];
+        }
+        set entities(value: EntityReference[]) {
+            pb_1.Message.setRepeatedWrapperField(this, 7, value);
+        }
+        get retryAfterMs() {
+            return pb_1.Message.getFieldWithDefault(this, 8, 0) as number;
+        }
+        set retryAfterMs(value: number) {
+            pb_1.Message.setField(this, 8, value);
+        }
         static fromObject(data: {
             status?: string;
             code?: number;
             scope?: number;
+            type?: number;
+            key?: string;
+            violations?: ReturnType<typeof FieldViolation.prototype.toObject>[];
+            entities?: ReturnType<typeof EntityReference.prototype.toObject>[];
+            retryAfterMs?: number;
         }): ResponseHeader {
             const message = new ResponseHeader({});
             if (data.status != null) {
@@ -172,6 +430,21 @@ export namespace brewtheory {
             if (data.scope != null) {
                 message.scope = data.scope;
             }
+            if (data.type != null) {
+                message.type = data.type;
+            }
+            if (data.key != null) {
+                message.key = data.key;
+            }
+            if (data.violations != null) {
+                message.violations = data.violations.map(item => FieldViolation.fromObject(item));
+            }
+            if (data.entities != null) {
+                message.entities = data.entities.map(item => EntityReference.fromObject(item));
+            }
+            if (data.retryAfterMs != null) {
+                message.retryAfterMs = data.retryAfterMs;
+            }
             return message;
         }
         toObject() {
@@ -179,6 +452,11 @@ export namespace brewtheory {
                 status?: string;
                 code?: number;
                 scope?: number;
+                type?: number;
+                key?: string;
+                violations?: ReturnType<typeof FieldViolation.prototype.toObject>[];
+                entities?: ReturnType<typeof EntityReference.prototype.toObject>[];
+                retryAfterMs?: number;
             } = {};
             if (this.status != null) {
                 data.status = this.status;
@@ -189,6 +467,21 @@ export namespace brewtheory {
             if (this.scope != null) {
                 data.scope = this.scope;
             }
+            if (this.type != null) {
+                data.type = this.type;
+            }
+            if (this.key != null) {
+                data.key = this.key;
+            }
+            if (this.violations != null) {
+                data.violations = this.violations.map((item: FieldViolation) => item.toObject());
+            }
+            if (this.entities != null) {
+                data.entities = this.entities.map((item: EntityReference) => item.toObject());
+            }
+            if (this.retryAfterMs != null) {
+                data.retryAfterMs = this.retryAfterMs;
+            }
             return data;
         }
         serialize(): Uint8Array;
@@ -201,6 +494,16 @@ export namespace brewtheory {
                 writer.writeInt32(2, this.code);
             if (this.scope != 0)
                 writer.writeInt32(3, this.scope);
+            if (this.type != 0)
+                writer.writeInt32(4, this.type);
+            if (this.key.length)
+                writer.writeString(5, this.key);
+            if (this.violations.length)
+                writer.writeRepeatedMessage(6, this.violations, (item: FieldViolation) => item.serialize(writer));
+            if (this.entities.length)
+                writer.writeRepeatedMessage(7, this.entities, (item: EntityReference) => item.serialize(writer));
+            if (this.retryAfterMs != 0)
+                writer.writeInt64(8, this.retryAfterMs);
             if (!w)
                 return writer.getResultBuffer();
         }
@@ -219,6 +522,21 @@ export namespace brewtheory {
                     case 3:
                         message.scope = reader.readInt32();
                         break;
+                    case 4:
+                        message.type = reader.readInt32();
+                        break;
+                    case 5:
+                        message.key = reader.readString();
+                        break;
+                    case 6:
+                        reader.readMessage(message.violations, () => pb_1.Message.addToRepeatedWrapperField(message, 6, FieldViolation.deserialize(reader), FieldViolation));
+                        break;
+                    case 7:
+                        reader.readMessage(message.entities, () => pb_1.Message.addToRepeatedWrapperField(message, 7, EntityReference.deserialize(reader), EntityReference));
+                        break;
+                    case 8:
+                        message.retryAfterMs = reader.readInt64();
+                        break;
                     default: reader.skipField();
                 }
             }
@@ -11,9 +11,13 @@ export namespace brewtheory {
         constructor(data?: any[] | {
             header?: dependency_1.brewtheory.RequestHeader;
             publicKey?: Uint8Array;
+            bootstrapProof?: Uint8Array;
+            protocolVersion?: number;
+            appVersion?: string;
+            capabilities?: string[];
         }) {
             super();
-            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [], this.#one_of_decls);
+            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [6], this.#one_of_decls);
             if (!Array.isArray(data) && typeof data == "object") {
                 if ("header" in data && data.header != undefined) {
                     this.header = data.header;
@@ -21,6 +25,18 @@ export namespace brewtheory {
                 if ("publicKey" in data && data.publicKey != undefined) {
                     this.publicKey = data.publicKey;
                 }
+                if ("bootstrapProof" in data && data.bootstrapProof != undefined) {
+                    this.bootstrapProof = data.bootstrapProof;
+                }
+                if ("protocolVersion" in data && data.protocolVersion != undefined) {
+                    this.protocolVersion = data.protocolVersion;
+                }
+                if ("appVersion" in data && data.appVersion != undefined) {
+                    this.appVersion = data.appVersion;
+                }
+                if ("capabilities" in data && data.capabilities != undefined) {
+                    this.capabilities = data.capabilities;
+                }
             }
         }
         get header() {
@@ -38,9 +54,37 @@ export namespace brewtheory {
         set publicKey(value: Uint8Array) {
             pb_1.Message.setField(this, 2, value);
         }
+        get bootstrapProof() {
+            return pb_1.Message.getFieldWithDefault(this, 3, new Uint8Array()) as Uint8Array;
+        }
+        set bootstrapProof(value: Uint8Array) {
+            pb_1.Message.setField(this, 3, value);
+        }
+        get protocolVersion() {
+            return pb_1.Message.getFieldWithDefault(this, 4, 0) as number;
+        }
+        set protocolVersion(value: number) {
+            pb_1.Message.setField(this, 4, value);
+        }
+        get appVersion() {
+            return pb_1.Message.getFieldWithDefault(this, 5, "") as string;
+        }
+        set appVersion(value: string) {
+            pb_1.Message.setField(this, 5, value);
+        }
+        get capabilities() {
+            return pb_1.Message.getFieldWithDefault(this, 6, []) as string[];
+        }
+        set capabilities(value: string[]) {
+            pb_1.Message.setField(this, 6, value);
+        }
         static fromObject(data: {
             header?: ReturnType<typeof dependency_1.brewtheory.RequestHeader.prototype.toObject>;
             publicKey?: Uint8Array;
+            bootstrapProof?: Uint8Array;
+            protocolVersion?: number;
+            appVersion?: string;
+            capabilities?: string[];
         }): KeyExchangeRequest {
             const message = new KeyExchangeRequest({});
             if (data.header != null) {
@@ -49,12 +93,28 @@ export namespace brewtheory {
             if (data.publicKey != null) {
                 message.publicKey = data.publicKey;
             }
+            if (data.bootstrapProof != null) {
+                message.bootstrapProof = data.bootstrapProof;
+            }
+            if (data.protocolVersion != null) {
+                message.protocolVersion = data.protocolVersion;
+            }
+            if (data.appVersion != null) {
+                message.appVersion = data.appVersion;
+            }
+            if (data.capabilities != null) {
+                message.capabilities = data.capabilities;
+            }
             return message;
         }
         toObject() {
             const data: {
                 header?: ReturnType<typeof dependency_1.brewtheory.RequestHeader.prototype.toObject>;
                 publicKey?: Uint8Array;
+                bootstrapProof?: Uint8Array;
+                protocolVersion?: number;
+                appVersion?: string;
+                capabilities?: string[];
             } = {};
             if (this.header != null) {
                 data.header = this.header.toObject();
@@ -62,6 +122,18 @@ export namespace brewtheory {
             if (this.publicKey != null) {
                 data.publicKey = this.publicKey;
             }
+            if (this.bootstrapProof != null) {
+                data.bootstrapProof = this.bootstrapProof;
+            }
+            if (this.protocolVersion != null) {
+                data.protocolVersion = this.protocolVersion;
+            }
+            if (this.appVersion != null) {
+                data.appVersion = this.appVersion;
+            }
+            if (this.capabilities != null) {
+                data.capabilities = this.capabilities;
+            }
             return data;
         }
         serialize(): Uint8Array;
@@ -72,6 +144,14 @@ export namespace brewtheory {
                 writer.writeMessage(1, this.header, () => this.header.serialize(writer));
             if (this.publicKey.length)
                 writer.writeBytes(2, this.publicKey);
+            if (this.bootstrapProof.length)
+                writer.writeBytes(3, this.bootstrapProof);
+            if (this.protocolVersion != 0)
+                writer.writeInt32(4, this.protocolVersion);
+            if (this.appVersion.length)
+                writer.writeString(5, this.appVersion);
+            if (this.capabilities.length)
+                writer.writeRepeatedString(6, this.capabilities);
             if (!w)
                 return writer.getResultBuffer();
         }
@@ -87,6 +167,18 @@ export namespace brewtheory {
                     case 2:
                         message.publicKey = reader.readBytes();
                         break;
+                    case 3:
+                        message.bootstrapProof = reader.readBytes();
+                        break;
+                    case 4:
+                        message.protocolVersion = reader.readInt32();
+                        break;
+                    case 5:
+                        message.appVersion = reader.readString();
+                        break;
+                    case 6:
+                        pb_1.Message.addToRepeatedField(message, 6, reader.readString());
+                        break;
                     default: reader.skipField();
                 }
             }
@@ -105,9 +197,12 @@ export namespace brewtheory {
             header?: dependency_1.brewtheory.ResponseHeader;
             publicKey?: Uint8Array;
             token?: string;
+            protocolVersion?: number;
+            appVersion?: string;
+            capabilities?: string[];
         }) {
             super();
-            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [], this.#one_of_decls);
+            pb_1.Message.initialize(this, Array.isArray(data) ? data : [], 0, -1, [6], this.#one_of_decls);
             if (!Array.isArray(data) && typeof data == "object") {
                 if ("header" in data && data.header != undefined) {
                     this.header = data.header;
@@ -118,6 +213,15 @@ export namespace brewtheory {
                 if ("token" in data && data.token != undefined) {
                     this.token = data.token;
                 }
+                if ("protocolVersion" in data && data.protocolVersion != undefined) {
+                    this.protocolVersion = data.protocolVersion;
+                }
+                if ("appVersion" in data && data.appVersion != undefined) {
+                    this.appVersion = data.appVersion;
+                }
+                if ("capabilities" in data && data.capabilities != undefined) {
+                    this.capabilities = data.capabilities;
+                }
             }
         }
         get header() {
@@ -141,10 +245,31 @@ export namespace brewtheory {
         set token(value: string) {
             pb_1.Message.setField(this, 3, value);
         }
+        get protocolVersion() {
+            return pb_1.Message.getFieldWithDefault(this, 4, 0) as number;
+        }
+        set protocolVersion(value: number) {
+            pb_1.Message.setField(this, 4, value);
+        }
+        get appVersion() {
+            return pb_1.Message.getFieldWithDefault(this, 5, "") as string;
+        }
+        set appVersion(value: string) {
+            pb_1.Message.setField(this, 5, value);
+        }
+        get capabilities() {
+            return pb_1.Message.getFieldWithDefault(this, 6, []) as string[];
+        }
+        set capabilities(value: string[]) {
+            pb_1.Message.setField(this, 6, value);
+        }
         static fromObject(data: {
             header?: ReturnType<typeof dependency_1.brewtheory.ResponseHeader.prototype.toObject>;
             publicKey?: Uint8Array;
             token?: string;
+            protocolVersion?: number;
+            appVersion?: string;
+            capabilities?: string[];
         }): KeyExchangeResponse {
             const message = new KeyExchangeResponse({});
             if (data.header != null) {
@@ -156,6 +281,15 @@ export namespace brewtheory {
             if (data.token != null) {
                 message.token = data.token;
             }
+            if (data.protocolVersion != null) {
+                message.protocolVersion = data.protocolVersion;
+            }
+            if (data.appVersion != null) {
+                message.appVersion = data.appVersion;
+            }
+            if (data.capabilities != null) {
+                message.capabilities = data.capabilities;
+            }
             return message;
         }
         toObject() {
@@ -163,6 +297,9 @@ export namespace brewtheory {
                 header?: ReturnType<typeof dependency_1.brewtheory.ResponseHeader.prototype.toObject>;
                 publicKey?: Uint8Array;
                 token?: string;
+                protocolVersion?: number;
+                appVersion?: string;
+                capabilities?: string[];
             } = {};
             if (this.header != null) {
                 data.header = this.header.toObject();
@@ -173,6 +310,15 @@ export namespace brewtheory {
             if (this.token != null) {
                 data.token = this.token;
             }
+            if (this.protocolVersion != null) {
+                data.protocolVersion = this.protocolVersion;
+            }
+            if (this.appVersion != null) {
+                data.appVersion = this.appVersion;
+            }
+            if (this.capabilities != null) {
+                data.capabilities = this.capabilities;
+            }
             return data;
         }
         serialize(): Uint8Array;
@@ -185,6 +331,12 @@ export namespace brewtheory {
                 writer.writeBytes(2, this.publicKey);
             if (this.token.length)
                 writer.writeString(3, this.token);
+            if (this.protocolVersion != 0)
+                writer.writeInt32(4, this.protocolVersion);
+            if (this.appVersion.length)
+                writer.writeString(5, this.appVersion);
+            if (this.capabilities.length)
+                writer.writeRepeatedString(6, this.capabilities);
             if (!w)
                 return writer.getResultBuffer();
         }
@@ -203,6 +355,15 @@ export namespace brewtheory {
                     case 3:
                         message.token = reader.readString();
                         break;
+                    case 4:
+                        message.protocolVersion = reader.readInt32();
+                        break;
+                    case 5:
+                        message.appVersion = reader.readString();
+                        break;
+                    case 6:
+                        pb_1.Message.addToRepeatedField(message, 6, reader.readString());
+                        break;
                     default: reader.skipField();
                 }
             }
@@ -16,11 +16,30 @@ You should have received a copy of the GNU General Public License
 along with this program.  If not, see <http://www.gnu.org/licenses/>.
 */
 
+import * as ed from '@noble/ed25519';
 import Endpoint from './Endpoint';
-import Client from './Client';
+import Client, { ENVELOPE_REQUEST } from './Client';
 
 class AjaxClient extends Client {
-  request(method: string, payload: Uint8Array | null): Promise<boolean> {
+  async request(
+    method: string,
+    payload: Uint8Array | null,
+    timeout?: number
+  ): Promise<boolean> {
+    this.sendCounter += 1;
+    const timestamp = Date.now();
+    let signature = '';
+    if (payload !== null) {
+      const envelope = await this.createEnvelope(
+        ENVELOPE_REQUEST,
+        method,
+        this.sendCounter,
+        timestamp,
+        payload
+      );
+      signature = await this.createSignature(envelope);
+    }
+
     const req = new XMLHttpRequest();
     const promise = new Promise<boolean>((resolve): void => {
       req.onreadystatechange = () => {
@@ -40,21 +59,23 @@ class AjaxClient extends Client {
         }
       };
 
-      this.sendCounter += 1;
-
       // 3rd param = async - true/false
       req.open('POST', Endpoint.endpoint, false);
 
       req.setRequestHeader('Request-Method', method);
       req.setRequestHeader('Message-Sequence', this.sendCounter.toString());
+      req.setRequestHeader('Message-Timestamp', timestamp.toString());
       req.setRequestHeader('Client-Token', this.clientToken);
+      if (timeout !== undefined) {
+        req.setRequestHeader('Request-Timeout', timeout.toString());
+      }
 
       if (payload !== null) {
-        const signature = this.createSignature(payload);
         req.setRequestHeader('Message-Signature', signature);
+        req.send(ed.utils.bytesToHex(payload));
+      } else {
+        req.send(null);
       }
-
-      req.send(payload);
     });
     return promise;
   }
@@ -23,6 +23,17 @@ import ClientInterface from '../interfaces/rpc/Client';
 import ResponseInterface from '../interfaces/rpc/Response';
 import InternalError from '../core/InternalError';
 
+/**
+ * Envelope kinds keep request & response signatures from being interchangeable
+ */
+export const ENVELOPE_REQUEST = 'brewtheory-request-v1';
+export const ENVELOPE_RESPONSE = 'brewtheory-response-v1';
+
+/**
+ * How far a response timestamp may drift from the local clock
+ */
+const MAX_CLOCK_SKEW = 30000;
+
 /**
  *
  */
@@ -58,6 +69,11 @@ class Client implements ClientInterface {
    */
   clientToken: string;
 
+  /**
+   * The optional features negotiated with the backend during key exchange
+   */
+  capabilities: Array<string> = [];
+
   /**
    * The SSL certificate created by the backend process
    */
@@ -99,19 +115,63 @@ class Client implements ClientInterface {
    *
    * @param _method
    * @param _payload
+   * @param _timeout
    */
-  request(_method: string, _payload: Uint8Array | null): Promise<boolean> {
+  request(
+    _method: string,
+    _payload: Uint8Array | null,
+    _timeout?: number
+  ): Promise<boolean> {
     const response = new Promise<boolean>(() => {});
     return response;
   }
 
+  /**
+   * Create the SHA-256 digest of a message body
+   *
+   * @param payload The decoded message body
+   */
+  async digest(payload: Uint8Array): Promise<Uint8Array> {
+    const hash = await crypto.subtle.digest('SHA-256', payload);
+    return new Uint8Array(hash);
+  }
+
+  /**
+   * Build the canonical envelope that is signed for a message.
+   * This must match the envelope encoding used by the backend.
+   *
+   * @param kind Whether this is a request or response envelope
+   * @param method The API method name
+   * @param sequence The message sequence number
+   * @param timestamp The message timestamp in unix milliseconds
+   * @param payload The decoded message body
+   */
+  async createEnvelope(
+    kind: string,
+    method: string,
+    sequence: number,
+    timestamp: number,
+    payload: Uint8Array
+  ): Promise<Uint8Array> {
+    const digest = ed.utils.bytesToHex(await this.digest(payload));
+    const envelope = [
+      kind,
+      method,
+      this.clientToken,
+      sequence.toString(),
+      timestamp.toString(),
+      digest,
+    ].join('\n');
+    return new TextEncoder().encode(envelope);
+  }
+
   /**
    * Create the signature for a request message
    *
-   * @param payload The body of the request message
+   * @param envelope The canonical request envelope
    */
-  async createSignature(payload: string): Promise<string> {
-    const rawSignature = await ed.sign(payload, this.signPrivateKey);
+  async createSignature(envelope: Uint8Array): Promise<string> {
+    const rawSignature = await ed.sign(envelope, this.signPrivateKey);
     const signature = Buffer.from(rawSignature).toString('hex');
     return signature;
   }
@@ -120,10 +180,13 @@ class Client implements ClientInterface {
    * Verify the signature of a response message
    *
    * @param signature The response message signature
-   * @param payload The message body
+   * @param envelope The canonical response envelope
    */
-  async verifySignature(signature: string, payload: string): Promise<boolean> {
-    const isValid = await ed.verify(signature, payload, this.verifyPublicKey);
+  async verifySignature(
+    signature: string,
+    envelope: Uint8Array
+  ): Promise<boolean> {
+    const isValid = await ed.verify(signature, envelope, this.verifyPublicKey);
     return isValid;
   }
 
@@ -154,12 +217,34 @@ class Client implements ClientInterface {
       throw new InternalError('Transport Error', 'Unexpected sequence');
     }
 
+    if (!('message-timestamp' in response.headers)) {
+      throw new InternalError('Transport Error', 'Missing timestamp header');
+    }
+    const timestamp = parseInt(
+      response.headers['message-timestamp'] as string,
+      10
+    );
+    if (
+      Number.isNaN(timestamp) ||
+      Math.abs(Date.now() - timestamp) > MAX_CLOCK_SKEW
+    ) {
+      throw new InternalError('Transport Error', 'Unexpected timestamp');
+    }
+
     if (!('message-signature' in response.headers)) {
       throw new InternalError('Transport Error', 'Missing message signature');
     }
 
     const signature = response.headers['message-signature'] as string;
-    const ok = await this.verifySignature(signature, response.body);
+    const method = (response.headers['request-method'] as string) || '';
+    const envelope = await this.createEnvelope(
+      ENVELOPE_RESPONSE,
+      method,
+      sequence,
+      timestamp,
+      ed.utils.hexToBytes(response.body)
+    );
+    const ok = await this.verifySignature(signature, envelope);
     if (!ok) {
       throw new InternalError(
         'Transport Error',
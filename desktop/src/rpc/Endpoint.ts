@@ -21,6 +21,28 @@ const Endpoint = {
   host: 'localhost',
   path: '/rpc',
   endpoint: 'https://localhost:53017/rpc',
+  // SHA-256 fingerprint of the certificate the backend announced
+  fingerprint: '',
 };
 
+/**
+ * Point RPC requests at the port the backend announced
+ *
+ * @param port
+ */
+export function setEndpointPort(port: number): void {
+  Endpoint.port = port;
+  Endpoint.endpoint = `https://${Endpoint.host}:${port}${Endpoint.path}`;
+}
+
+/**
+ * Pin the certificate the backend announced.
+ * Connections presenting any other certificate are refused.
+ *
+ * @param fingerprint Hex encoded SHA-256 digest of the certificate
+ */
+export function setEndpointFingerprint(fingerprint: string): void {
+  Endpoint.fingerprint = fingerprint.toLowerCase();
+}
+
 export default Endpoint;
@@ -18,15 +18,56 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 import * as https from 'https';
 import * as http from 'http';
+import { Socket } from 'net';
+import { TLSSocket } from 'tls';
+import { createHash } from 'crypto';
 import * as ed from '@noble/ed25519';
 import Endpoint from './Endpoint';
 import Response from './Response';
-import Client from './Client';
+import Client, { ENVELOPE_REQUEST } from './Client';
 
 /**
  * This is a native request using the built-in nodejs modules
  */
 class NativeClient extends Client {
+  /**
+   * The main process doesn't have web crypto available, so use the node
+   * crypto module for body digests
+   *
+   * @param payload The decoded message body
+   */
+  async digest(payload: Uint8Array): Promise<Uint8Array> {
+    return new Uint8Array(createHash('sha256').update(payload).digest());
+  }
+
+  /**
+   * Refuse to talk to a backend that doesn't present the certificate it
+   * announced.  This also covers requests made before the certificate file
+   * has been loaded, when the usual chain checks are skipped.
+   *
+   * @param req
+   * @param socket
+   */
+  static pinCertificate(req: http.ClientRequest, socket: Socket): void {
+    if (Endpoint.fingerprint === '' || !(socket instanceof TLSSocket)) {
+      return;
+    }
+    const check = (): void => {
+      const fingerprint = (socket.getPeerCertificate().fingerprint256 ?? '')
+        .replace(/:/g, '')
+        .toLowerCase();
+      if (fingerprint !== Endpoint.fingerprint) {
+        req.destroy(new Error('Backend certificate does not match'));
+      }
+    };
+    // kept alive sockets have already completed their handshake
+    if (socket.getPeerCertificate().fingerprint256 !== undefined) {
+      check();
+    } else {
+      socket.once('secureConnect', check);
+    }
+  }
+
   /**
    * Make an API request
    * The direct response is not returned, but can be accessed from the client
@@ -34,9 +75,14 @@ class NativeClient extends Client {
    *
    * @param method
    * @param payload
+   * @param timeout Milliseconds the backend may spend on the call
    * @returns a promise indicating whether the request was successful or not
    */
-  async request(method: string, payload: Uint8Array | null): Promise<boolean> {
+  async request(
+    method: string,
+    payload: Uint8Array | null,
+    timeout?: number
+  ): Promise<boolean> {
     this.sendCounter += 1;
     const options: https.RequestOptions = {
       hostname: Endpoint.host,
@@ -53,16 +99,29 @@ class NativeClient extends Client {
       // we haven't loaded the cert yet, so ignore the ssl errors on this request
       options.rejectUnauthorized = false;
     }
+    const timestamp = Date.now();
     options.headers = {
       'Request-Method': method,
       'Message-Sequence': this.sendCounter,
+      'Message-Timestamp': timestamp,
       'Client-Token': this.clientToken,
     };
+    if (timeout !== undefined) {
+      // the backend stops the call once this many milliseconds have passed
+      options.headers['Request-Timeout'] = timeout;
+    }
     let requestBody = '';
     if (payload !== null) {
       // hex encode the payload so we don't have to worry about the protobuf wire format getting mangled during transit.
       requestBody = ed.utils.bytesToHex(payload);
-      const signature = await this.createSignature(requestBody);
+      const envelope = await this.createEnvelope(
+        ENVELOPE_REQUEST,
+        method,
+        this.sendCounter,
+        timestamp,
+        payload
+      );
+      const signature = await this.createSignature(envelope);
       options.headers['Message-Signature'] = signature;
       options.headers['Content-Type'] = 'application/octet-stream';
     }
@@ -91,6 +150,7 @@ class NativeClient extends Client {
           });
         }
       );
+      req.on('socket', (socket) => NativeClient.pinCertificate(req, socket));
       req.on('error', (err) => {
         this.lastError = err;
         resolve(false);
@@ -98,10 +98,14 @@ export default class Rpc implements RpcInterface {
    *
    * @param method The API method name
    * @param payload
-   * @param callback
+   * @param timeout Milliseconds the backend may spend on the call
    */
-  async request(method: string, payload: Uint8Array): Promise<string> {
-    const response = await this.client.request(method, payload);
+  async request(
+    method: string,
+    payload: Uint8Array,
+    timeout?: number
+  ): Promise<string> {
+    const response = await this.client.request(method, payload, timeout);
     if (response === true && this.client.lastResponse !== null) {
       return this.client.lastResponse.body;
     }
@@ -1,8 +1,9 @@
 module github.com/farrcraft/brewtheory
 
-go 1.19
+go 1.22
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/shibukawa/configdir v0.0.0-20170330084843-e180dbdc8da0
 	github.com/sirupsen/logrus v1.9.0
 	github.com/urfave/cli/v2 v2.11.1
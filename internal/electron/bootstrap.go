@@ -0,0 +1,70 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package electron
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// BootstrapEnv is the environment variable the launching process can use to
+// pass the hex encoded bootstrap secret
+const BootstrapEnv = "BREWTHEORY_BOOTSTRAP_SECRET"
+
+// ReadBootstrapSecret reads the bootstrap secret shared by the launching
+// process.  When fd is negative the secret comes from the environment & the
+// variable is cleared so it isn't inherited any further.  Otherwise the first
+// line read from the file descriptor is used, which lets the launcher hand it
+// over on stdin (fd 0) or an inherited pipe.
+// A nil secret means none was provided.
+func ReadBootstrapSecret(fd int) ([]byte, error) {
+	var encoded string
+	if fd < 0 {
+		encoded = os.Getenv(BootstrapEnv)
+		os.Unsetenv(BootstrapEnv)
+	} else {
+		file := os.NewFile(uintptr(fd), "bootstrap")
+		if file == nil {
+			return nil, fmt.Errorf("invalid bootstrap file descriptor [%d]", fd)
+		}
+		line, err := bufio.NewReader(file).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("error reading bootstrap secret - %w", err)
+		}
+		encoded = line
+	}
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	secret, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding bootstrap secret - %w", err)
+	}
+	if len(secret) < rpc.MinBootstrapSecretSize {
+		return nil, fmt.Errorf("bootstrap secret must be at least [%d] bytes", rpc.MinBootstrapSecretSize)
+	}
+	return secret, nil
+}
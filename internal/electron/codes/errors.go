@@ -19,8 +19,10 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package codes
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 )
 
 // Code is the error code type
@@ -38,6 +40,44 @@ type InternalError struct {
 	Code    Code
 	Type    ErrorType
 	Message string
+	// Key is a stable name for the error the frontend can localize the
+	// message with.  It defaults to the name of the code.
+	Key string
+	// Violations point at the request fields that are invalid
+	Violations []FieldViolation
+	// Entities identify the records the error is about
+	Entities []EntityReference
+	// RetryAfter is set when the request may succeed if it is retried later
+	RetryAfter time.Duration
+
+	// cause is the error this one was created from.  It is kept for logging &
+	// errors.Is/As but is never sent to the client.
+	cause error
+}
+
+// FieldViolation points at a request field that is invalid
+type FieldViolation struct {
+	// Field is the path of the field, e.g. "steps[2].temperature"
+	Field string
+	// Key is a stable name for the problem, e.g. "required"
+	Key         string
+	Description string
+}
+
+// EntityReference identifies a record an error is about
+type EntityReference struct {
+	Type string
+	ID   string
+}
+
+// Error lets a violation be found with errors.As
+func (violation FieldViolation) Error() string {
+	return fmt.Sprint(violation.Field, " - ", violation.Key)
+}
+
+// Error lets a reference be found with errors.As
+func (entity EntityReference) Error() string {
+	return fmt.Sprint(entity.Type, " [", entity.ID, "]")
 }
 
 // These are the status types that can be passed to the front end
@@ -91,6 +131,32 @@ const (
 	ErrorDelete
 	ErrorCreate
 	ErrorRecordMissing
+	ErrorPanic
+	ErrorInvalidRequest
+	ErrorBadRequest
+	ErrorUnsupportedVerb
+	ErrorUnknownPath
+	ErrorUnknownMethod
+	ErrorUnauthenticated
+	ErrorBadSignature
+	ErrorBadSequence
+	ErrorTooManySessions
+	ErrorBadTimestamp
+	ErrorBadBootstrapProof
+	ErrorPairingLocked
+	ErrorAborted
+	ErrorIncompatibleProtocol
+	ErrorUnsupportedEncoding
+	ErrorListen
+	ErrorIdentity
+	ErrorTLSRequired
+	ErrorBootstrapSecret
+	ErrorCancelled
+	ErrorDeadlineExceeded
+	ErrorUnknownJob
+	ErrorBodyTooLarge
+	ErrorRateLimited
+	ErrorAudit
 )
 
 // String converts error code to a string
@@ -112,30 +178,170 @@ func New(scope Scope, code Code) *InternalError {
 		Code:    code,
 		Scope:   scope,
 		Message: msg,
+		Key:     KeyFromCode(code),
 	}
 	return err
 }
 
+// NewApplication creates an InternalError for a failure that can happen
+// during normal use, such as invalid input
+func NewApplication(scope Scope, code Code) *InternalError {
+	err := New(scope, code)
+	err.Type = TypeApplication
+	return err
+}
+
+// Wrap creates an InternalError caused by another error.
+// The cause can be found with errors.Is & errors.As, but only the code is
+// ever sent to the client.
+func Wrap(scope Scope, code Code, cause error) *InternalError {
+	err := New(scope, code)
+	err.cause = cause
+	return err
+}
+
+// WithKey replaces the message key
+func (error *InternalError) WithKey(key string) *InternalError {
+	error.Key = key
+	return error
+}
+
+// WithField adds a field violation
+func (error *InternalError) WithField(field string, key string, description string) *InternalError {
+	error.Violations = append(error.Violations, FieldViolation{Field: field, Key: key, Description: description})
+	return error
+}
+
+// WithEntity adds a reference to a record the error is about
+func (error *InternalError) WithEntity(kind string, id string) *InternalError {
+	error.Entities = append(error.Entities, EntityReference{Type: kind, ID: id})
+	return error
+}
+
+// WithRetry tells the client it may retry the request after a delay
+func (error *InternalError) WithRetry(delay time.Duration) *InternalError {
+	error.RetryAfter = delay
+	return error
+}
+
 // Error satisfies the error type interface
 func (error *InternalError) Error() string {
+	if error.cause != nil {
+		return fmt.Sprint(error.Message, " - ", error.cause)
+	}
 	return error.Message
 }
 
-// IsInternalError tests to see if this is an internal error or a native error type
-func IsInternalError(err error) bool {
-	if _, ok := err.(*InternalError); ok {
-		return true
+// Unwrap returns the error this one was created from, if any
+func (error *InternalError) Unwrap() error {
+	return error.cause
+}
+
+// Is matches another InternalError with the same scope & code, so errors can
+// be compared with errors.Is(err, codes.New(scope, code))
+func (error *InternalError) Is(target error) bool {
+	other, ok := target.(*InternalError)
+	if !ok {
+		return false
+	}
+	return other.Scope == error.Scope && other.Code == error.Code
+}
+
+// As finds the first field violation or entity reference when errors.As is
+// given a *FieldViolation or *EntityReference
+func (error *InternalError) As(target any) bool {
+	switch target := target.(type) {
+	case *FieldViolation:
+		if len(error.Violations) != 0 {
+			*target = error.Violations[0]
+			return true
+		}
+	case *EntityReference:
+		if len(error.Entities) != 0 {
+			*target = error.Entities[0]
+			return true
+		}
 	}
 	return false
 }
 
-// ToInternalError converts an error to an InternalError
+// IsInternalError tests to see if an error is, or wraps, an internal error
+func IsInternalError(err error) bool {
+	var internal *InternalError
+	return errors.As(err, &internal)
+}
+
+// ToInternalError converts an error to an InternalError.
+// Errors that don't wrap an InternalError are replaced by an
+// ErrorInternalEscape that keeps them as its cause.
 func ToInternalError(err error) *InternalError {
-	if internal, ok := err.(*InternalError); ok {
+	var internal *InternalError
+	if errors.As(err, &internal) {
 		return internal
 	}
-	code := New(ScopeGeneral, ErrorInternalEscape)
-	return code
+	return Wrap(ScopeGeneral, ErrorInternalEscape, err)
+}
+
+// codeKeys are the stable names of the error codes.  The frontend localizes
+// messages by key, so a key must never change once it has been released.
+var codeKeys = map[Code]string{
+	ErrorOK:                   "ok",
+	ErrorUnknown:              "unknown",
+	ErrorInternalEscape:       "internal_escape",
+	ErrorUnauthorized:         "unauthorized",
+	ErrorInvalidType:          "invalid_type",
+	ErrorMarshal:              "marshal",
+	ErrorOpenKey:              "open_key",
+	ErrorEncrypt:              "encrypt",
+	ErrorDecrypt:              "decrypt",
+	ErrorCrypto:               "crypto",
+	ErrorWriteBucket:          "write_bucket",
+	ErrorSave:                 "save",
+	ErrorBucketMissing:        "bucket_missing",
+	ErrorDecode:               "decode",
+	ErrorDeriveKey:            "derive_key",
+	ErrorConvertID:            "convert_id",
+	ErrorLookup:               "lookup",
+	ErrorLoad:                 "load",
+	ErrorLoadAll:              "load_all",
+	ErrorDelete:               "delete",
+	ErrorCreate:               "create",
+	ErrorRecordMissing:        "record_missing",
+	ErrorPanic:                "panic",
+	ErrorInvalidRequest:       "invalid_request",
+	ErrorBadRequest:           "bad_request",
+	ErrorUnsupportedVerb:      "unsupported_verb",
+	ErrorUnknownPath:          "unknown_path",
+	ErrorUnknownMethod:        "unknown_method",
+	ErrorUnauthenticated:      "unauthenticated",
+	ErrorBadSignature:         "bad_signature",
+	ErrorBadSequence:          "bad_sequence",
+	ErrorTooManySessions:      "too_many_sessions",
+	ErrorBadTimestamp:         "bad_timestamp",
+	ErrorBadBootstrapProof:    "bad_bootstrap_proof",
+	ErrorPairingLocked:        "pairing_locked",
+	ErrorAborted:              "aborted",
+	ErrorIncompatibleProtocol: "incompatible_protocol",
+	ErrorUnsupportedEncoding:  "unsupported_encoding",
+	ErrorListen:               "listen",
+	ErrorIdentity:             "identity",
+	ErrorTLSRequired:          "tls_required",
+	ErrorBootstrapSecret:      "bootstrap_secret",
+	ErrorCancelled:            "cancelled",
+	ErrorDeadlineExceeded:     "deadline_exceeded",
+	ErrorUnknownJob:           "unknown_job",
+	ErrorBodyTooLarge:         "body_too_large",
+	ErrorRateLimited:          "rate_limited",
+	ErrorAudit:                "audit",
+}
+
+// KeyFromCode returns the stable name of an error code
+func KeyFromCode(code Code) string {
+	key, ok := codeKeys[code]
+	if !ok {
+		return codeKeys[ErrorUnknown]
+	}
+	return key
 }
 
 func messageFromScope(scope Scope) string {
@@ -147,6 +353,8 @@ func messageFromScope(scope Scope) string {
 		msgScope = "db"
 	case ScopeGeneral:
 		msgScope = "general"
+	case ScopeRPC:
+		msgScope = "rpc"
 	default:
 		msgScope = "default"
 	}
@@ -205,6 +413,58 @@ func defaultMessageFromCode(code Code) string {
 		msg = "error creating"
 	case ErrorRecordMissing:
 		msg = "error missing record"
+	case ErrorPanic:
+		msg = "error unexpected panic"
+	case ErrorInvalidRequest:
+		msg = "error invalid request"
+	case ErrorBadRequest:
+		msg = "error malformed request"
+	case ErrorUnsupportedVerb:
+		msg = "error unsupported http verb"
+	case ErrorUnknownPath:
+		msg = "error unknown path"
+	case ErrorUnknownMethod:
+		msg = "error unknown method"
+	case ErrorUnauthenticated:
+		msg = "error unauthenticated"
+	case ErrorBadSignature:
+		msg = "error bad signature"
+	case ErrorBadSequence:
+		msg = "error bad sequence"
+	case ErrorTooManySessions:
+		msg = "error too many sessions"
+	case ErrorBadTimestamp:
+		msg = "error bad timestamp"
+	case ErrorBadBootstrapProof:
+		msg = "error bad bootstrap proof"
+	case ErrorPairingLocked:
+		msg = "error pairing locked"
+	case ErrorAborted:
+		msg = "error aborted"
+	case ErrorIncompatibleProtocol:
+		msg = "error incompatible protocol version"
+	case ErrorUnsupportedEncoding:
+		msg = "error unsupported content encoding"
+	case ErrorListen:
+		msg = "error opening listener"
+	case ErrorIdentity:
+		msg = "error loading certificate"
+	case ErrorTLSRequired:
+		msg = "error tls required"
+	case ErrorBootstrapSecret:
+		msg = "error reading bootstrap secret"
+	case ErrorCancelled:
+		msg = "error call cancelled"
+	case ErrorDeadlineExceeded:
+		msg = "error call deadline exceeded"
+	case ErrorUnknownJob:
+		msg = "error unknown job"
+	case ErrorBodyTooLarge:
+		msg = "error request body too large"
+	case ErrorRateLimited:
+		msg = "error rate limited"
+	case ErrorAudit:
+		msg = "error reading audit journal"
 	}
 
 	return msg
@@ -0,0 +1,421 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package diag
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/farrcraft/brewtheory/internal/electron/jobs"
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// JobName is the name the diagnostics job runner is registered under
+const JobName = "diagnostics"
+
+// InputExcludeLogs is the job input that leaves logs out of the bundle
+const InputExcludeLogs = "exclude-logs"
+
+// DefaultMaxBundles is how many bundles the diagnostics job keeps
+const DefaultMaxBundles = 5
+
+// bundleTimeFormat is the timestamp in bundle file names
+const bundleTimeFormat = "20060102T150405.000"
+
+// ExcludedLogFields are blanked in bundled logs on top of the sensitive
+// fields, since logged responses & job inputs can hold recipe contents
+var ExcludedLogFields = []string{"response", "input"}
+
+// DatabaseCheck is the result of a database integrity check
+type DatabaseCheck struct {
+	// Status is "ok", "failed" or "unavailable"
+	Status  string    `json:"status"`
+	Detail  string    `json:"detail,omitempty"`
+	Checked time.Time `json:"checked"`
+}
+
+// DatabaseChecker checks the integrity of the database
+type DatabaseChecker func(ctx context.Context) DatabaseCheck
+
+// Collector gathers the contents of a diagnostic bundle: logs, sanitized
+// settings, build info, the certificate fingerprint, service stats, jobs &
+// database health.  Secrets, job inputs & logged message bodies are left out.
+type Collector struct {
+	Logger  *logrus.Logger
+	Version string
+	// Settings returns the bundle's config, which must already be sanitized
+	Settings func() interface{}
+	LogFile  *logging.RotatingFile
+	Identity *rpc.Identity
+	// Server provides live stats, it is nil when the service isn't running
+	Server *rpc.Server
+	// JobsPath is read for the job history when there is no live server
+	JobsPath string
+	// CheckDatabase is nil when there is no database to check
+	CheckDatabase DatabaseChecker
+	ExcludeLogs   bool
+}
+
+// section is one step of writing a bundle
+type section struct {
+	name  string
+	write func(ctx context.Context, archive *zip.Writer) error
+}
+
+// Write writes a zip archive of the bundle, reporting its progress as a
+// percentage
+func (collector *Collector) Write(ctx context.Context, out io.Writer, progress func(int32)) error {
+	archive := zip.NewWriter(out)
+	sections := []section{
+		{"manifest", collector.writeManifest},
+		{"build", collector.writeBuild},
+		{"config", collector.writeSettings},
+		{"certificate", collector.writeCertificate},
+		{"stats", collector.writeStats},
+		{"jobs", collector.writeJobs},
+		{"database", collector.writeDatabase},
+	}
+	if !collector.ExcludeLogs {
+		sections = append(sections, section{"logs", collector.writeLogs})
+	}
+	for i, section := range sections {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		err = section.write(ctx, archive)
+		if err != nil {
+			return fmt.Errorf("error writing %s - %w", section.name, err)
+		}
+		if progress != nil {
+			progress(int32((i + 1) * 100 / len(sections)))
+		}
+	}
+	return archive.Close()
+}
+
+// WriteFile writes a bundle into a directory & returns its path.
+// A partly written bundle is removed.
+func (collector *Collector) WriteFile(ctx context.Context, dir string, progress func(int32)) (string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("error creating diagnostics directory - %w", err)
+	}
+	path := filepath.Join(dir, FileName(time.Now()))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("error creating diagnostic bundle - %w", err)
+	}
+	err = collector.Write(ctx, file, progress)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// FileName names a bundle created at the given time
+func FileName(created time.Time) string {
+	return "brewtheory-diag-" + created.UTC().Format(bundleTimeFormat) + ".zip"
+}
+
+// Runner creates a job runner that writes bundles into a directory, keeping
+// the newest DefaultMaxBundles.  A job input of InputExcludeLogs leaves the
+// logs out.
+func (collector Collector) Runner(dir string) jobs.Runner {
+	return func(ctx context.Context, run *jobs.Run) error {
+		bundle := collector
+		bundle.ExcludeLogs = run.Input == InputExcludeLogs
+		path, err := bundle.WriteFile(ctx, dir, run.Progress)
+		if err != nil {
+			return err
+		}
+		run.Logf("diagnostic bundle written to %s", path)
+		prune(dir, DefaultMaxBundles)
+		return nil
+	}
+}
+
+// prune removes the oldest bundles in a directory beyond a limit
+func prune(dir string, keep int) {
+	bundles, err := filepath.Glob(filepath.Join(dir, "brewtheory-diag-*.zip"))
+	if err != nil {
+		return
+	}
+	sort.Strings(bundles)
+	for len(bundles) > keep {
+		os.Remove(bundles[0])
+		bundles = bundles[1:]
+	}
+}
+
+// create adds a compressed file to the archive, stamped with the current
+// time
+func create(archive *zip.Writer, name string) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+func writeJSON(archive *zip.Writer, name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	writer, err := create(archive, name)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func (collector *Collector) writeManifest(ctx context.Context, archive *zip.Writer) error {
+	manifest := struct {
+		Created     time.Time `json:"created"`
+		Version     string    `json:"version"`
+		Running     bool      `json:"running"`
+		ExcludeLogs bool      `json:"excludeLogs"`
+	}{
+		Created:     time.Now().UTC(),
+		Version:     collector.Version,
+		Running:     collector.Server != nil,
+		ExcludeLogs: collector.ExcludeLogs,
+	}
+	return writeJSON(archive, "manifest.json", manifest)
+}
+
+// buildSettings are the build settings copied into a bundle
+var buildSettings = []string{"vcs.revision", "vcs.time", "vcs.modified", "GOOS", "GOARCH", "CGO_ENABLED"}
+
+func (collector *Collector) writeBuild(ctx context.Context, archive *zip.Writer) error {
+	build := struct {
+		Version      string            `json:"version"`
+		GoVersion    string            `json:"goVersion"`
+		OS           string            `json:"os"`
+		Arch         string            `json:"arch"`
+		CPUs         int               `json:"cpus"`
+		Module       string            `json:"module,omitempty"`
+		Settings     map[string]string `json:"settings,omitempty"`
+		Dependencies []string          `json:"dependencies,omitempty"`
+	}{
+		Version:   collector.Version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+	}
+	info, ok := debug.ReadBuildInfo()
+	if ok {
+		build.Module = info.Main.Path
+		build.Settings = make(map[string]string)
+		for _, setting := range info.Settings {
+			for _, key := range buildSettings {
+				if setting.Key == key {
+					build.Settings[key] = setting.Value
+				}
+			}
+		}
+		for _, dep := range info.Deps {
+			build.Dependencies = append(build.Dependencies, dep.Path+"@"+dep.Version)
+		}
+	}
+	return writeJSON(archive, "build.json", build)
+}
+
+func (collector *Collector) writeSettings(ctx context.Context, archive *zip.Writer) error {
+	var settings interface{}
+	if collector.Settings != nil {
+		settings = collector.Settings()
+	}
+	return writeJSON(archive, "config.json", settings)
+}
+
+func (collector *Collector) writeCertificate(ctx context.Context, archive *zip.Writer) error {
+	certificate := struct {
+		Fingerprint string    `json:"fingerprint,omitempty"`
+		Subject     string    `json:"subject,omitempty"`
+		NotBefore   time.Time `json:"notBefore,omitempty"`
+		NotAfter    time.Time `json:"notAfter,omitempty"`
+		Error       string    `json:"error,omitempty"`
+	}{}
+	if collector.Identity == nil {
+		certificate.Error = "no config directory"
+		return writeJSON(archive, "certificate.json", certificate)
+	}
+	leaf, err := collector.Identity.ReadCertificate()
+	if err != nil {
+		certificate.Error = err.Error()
+	} else {
+		certificate.Fingerprint = rpc.CertificateFingerprint(leaf)
+		certificate.Subject = leaf.Subject.String()
+		certificate.NotBefore = leaf.NotBefore
+		certificate.NotAfter = leaf.NotAfter
+	}
+	return writeJSON(archive, "certificate.json", certificate)
+}
+
+// writeStats writes session & rejection counters along with the metrics of
+// a running server
+func (collector *Collector) writeStats(ctx context.Context, archive *zip.Writer) error {
+	server := collector.Server
+	if server == nil {
+		return writeJSON(archive, "stats.json", struct {
+			Running bool `json:"running"`
+		}{})
+	}
+
+	stats := struct {
+		Running  bool              `json:"running"`
+		Sessions int               `json:"sessions"`
+		Rejected map[string]uint64 `json:"rejected"`
+		Methods  map[string]int32  `json:"methods"`
+	}{
+		Running:  true,
+		Sessions: server.Sessions.Count(),
+		Rejected: server.Rejections.Counts(),
+		Methods:  make(map[string]int32, len(server.Methods)),
+	}
+	for name, method := range server.Methods {
+		stats.Methods[name] = method.Version
+	}
+	err := writeJSON(archive, "stats.json", stats)
+	if err != nil {
+		return err
+	}
+
+	writer, err := create(archive, "metrics.txt")
+	if err != nil {
+		return err
+	}
+	return server.WriteMetrics(writer)
+}
+
+// writeJobs writes the job history without inputs or logs, which may hold
+// recipe contents
+func (collector *Collector) writeJobs(ctx context.Context, archive *zip.Writer) error {
+	history := []jobs.Job{}
+	if collector.Server != nil && collector.Server.Jobs != nil {
+		history = collector.Server.Jobs.List()
+	} else if collector.JobsPath != "" {
+		saved, err := jobs.ReadFile(collector.JobsPath)
+		if err != nil {
+			collector.Logger.Warn("Error reading jobs for diagnostics - ", err)
+		}
+		for _, job := range saved {
+			history = append(history, *job)
+		}
+	}
+	for i := range history {
+		history[i].Input = ""
+		history[i].Log = nil
+	}
+	return writeJSON(archive, "jobs.json", history)
+}
+
+func (collector *Collector) writeDatabase(ctx context.Context, archive *zip.Writer) error {
+	check := DatabaseCheck{
+		Status:  "unavailable",
+		Detail:  "no database is configured",
+		Checked: time.Now().UTC(),
+	}
+	if collector.CheckDatabase != nil {
+		check = collector.CheckDatabase(ctx)
+	}
+	return writeJSON(archive, "database.json", check)
+}
+
+// writeLogs copies the log file & its rotated backups, redacting every line
+func (collector *Collector) writeLogs(ctx context.Context, archive *zip.Writer) error {
+	if collector.LogFile == nil {
+		return nil
+	}
+	paths, err := collector.LogFile.Backups()
+	if err != nil {
+		return err
+	}
+	paths = append(paths, collector.LogFile.Path)
+
+	fields := append(append([]string(nil), logging.DefaultSensitiveFields...), ExcludedLogFields...)
+	hook := &logging.RedactHook{Fields: fields}
+	for _, path := range paths {
+		err = copyLog(ctx, archive, path, hook)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyLog(ctx context.Context, archive *zip.Writer, path string, hook *logging.RedactHook) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer, err := create(archive, "logs/"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\n")
+		if len(line) > 0 {
+			_, err = writer.Write(append(hook.RedactLine(line), '\n'))
+			if err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package electron
+
+import (
+	"path/filepath"
+
+	"github.com/farrcraft/brewtheory/internal/electron/diag"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// Settings are the service options copied into diagnostic bundles.
+// Secrets are only reported as being set or not.
+type Settings struct {
+	Address         string            `json:"address"`
+	TLS             bool              `json:"tls"`
+	BootstrapSecret bool              `json:"bootstrapSecret"`
+	LockPairing     bool              `json:"lockPairing"`
+	JSONGateway     bool              `json:"jsonGateway"`
+	MetricsAddress  string            `json:"metricsAddress,omitempty"`
+	ShutdownTimeout string            `json:"shutdownTimeout"`
+	Limits          rpc.Limits        `json:"limits"`
+	LogFile         string            `json:"logFile,omitempty"`
+	LogLevel        string            `json:"logLevel"`
+	LogLevels       map[string]string `json:"logLevels,omitempty"`
+	LogMaxSize      int64             `json:"logMaxSize"`
+	LogMaxAge       string            `json:"logMaxAge"`
+	LogMaxBackups   int               `json:"logMaxBackups"`
+}
+
+// Settings returns the service's sanitized settings
+func (service *Electron) Settings() Settings {
+	settings := Settings{
+		Address:         service.Address,
+		TLS:             !service.DisableTLS,
+		BootstrapSecret: len(service.BootstrapSecret) != 0,
+		LockPairing:     service.LockPairing,
+		JSONGateway:     service.JSONGateway,
+		MetricsAddress:  service.MetricsAddress,
+		ShutdownTimeout: service.ShutdownTimeout.String(),
+		Limits:          service.Limits,
+		LogLevel:        service.LogOptions.Level,
+		LogLevels:       service.LogOptions.Levels,
+		LogMaxSize:      service.LogOptions.MaxSize,
+		LogMaxAge:       service.LogOptions.MaxAge.String(),
+		LogMaxBackups:   service.LogOptions.MaxBackups,
+	}
+	if service.LogFile != nil {
+		settings.LogFile = service.LogFile.Path
+	}
+	// report the levels in effect, which may have been changed at runtime
+	if service.Logs != nil {
+		settings.LogLevels = make(map[string]string)
+		for _, level := range service.Logs.Levels() {
+			settings.LogLevels[level.Name] = level.Level.String()
+		}
+	}
+	return settings
+}
+
+// Diagnostics creates a collector for diagnostic bundles.
+// Live stats are included once the RPC server has been created.
+func (service *Electron) Diagnostics() *diag.Collector {
+	collector := &diag.Collector{
+		Logger:   service.Logs.Get("diag"),
+		Version:  Version,
+		Settings: func() interface{} { return service.Settings() },
+		LogFile:  service.LogFile,
+		Server:   service.RPC,
+	}
+	dir, ok := rpc.ConfigDir(service.Logger)
+	if ok {
+		collector.JobsPath = filepath.Join(dir, jobsFile)
+	}
+	collector.Identity, _ = rpc.NewIdentity(service.Logger)
+	return collector
+}
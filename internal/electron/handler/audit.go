@@ -0,0 +1,80 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	"time"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// QueryAudit returns the audit entries matching a filter, oldest first
+func QueryAudit(context *rpc.RequestContext, request *messages.QueryAuditRequest) (*messages.QueryAuditResponse, error) {
+	if context.Server.Audit == nil {
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorAudit)
+	}
+	filter := rpc.AuditFilter{
+		Method:     request.Method,
+		Session:    request.Session,
+		EntityType: request.EntityType,
+		EntityID:   request.EntityId,
+		Limit:      int(request.Limit),
+	}
+	if request.Since > 0 {
+		filter.Since = time.UnixMilli(request.Since)
+	}
+	if request.Until > 0 {
+		filter.Until = time.UnixMilli(request.Until)
+	}
+	entries, err := context.Server.Audit.Query(filter)
+	if err != nil {
+		return nil, codes.Wrap(codes.ScopeRPC, codes.ErrorAudit, err)
+	}
+
+	response := &messages.QueryAuditResponse{}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, AuditEntryMessage(entry))
+	}
+	return response, nil
+}
+
+// AuditEntryMessage converts an audit entry into its RPC message
+func AuditEntryMessage(entry rpc.AuditEntry) *messages.AuditEntry {
+	message := &messages.AuditEntry{
+		Timestamp:  entry.Time.UnixMilli(),
+		Session:    entry.Session,
+		ApiToken:   entry.APIToken,
+		Method:     entry.Method,
+		Sequence:   entry.Sequence,
+		Code:       int32(entry.Code),
+		Scope:      int32(entry.Scope),
+		RolledBack: entry.RolledBack,
+	}
+	for _, change := range entry.Changes {
+		message.Changes = append(message.Changes, &messages.AuditChange{
+			Type:   change.Type,
+			Id:     change.ID,
+			Before: change.Before,
+			After:  change.After,
+		})
+	}
+	return message
+}
@@ -0,0 +1,135 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// Batch runs a list of calls in order & returns each of their results.
+// The whole batch is a single signed request & response.  In atomic mode
+// every call is checked before any of them run & methods that change state
+// without being able to undo it are refused.  The batch stops at the first
+// failure, the changes of the calls before it are rolled back & the calls
+// after it are reported as aborted.  Calls that haven't started when the
+// batch is cancelled are reported as cancelled.
+func Batch(context *rpc.RequestContext, request *messages.BatchRequest) (*messages.BatchResponse, error) {
+	server := context.Server
+	response := &messages.BatchResponse{
+		Header:  rpc.NewResponseHeader(),
+		Results: make([]*messages.BatchResult, len(request.Calls)),
+	}
+
+	for i, call := range request.Calls {
+		response.Results[i] = &messages.BatchResult{Header: rpc.NewResponseHeader()}
+		err := batchable(server, call.Method, request.Atomic)
+		if err != nil {
+			rpc.SetInternalError(response.Results[i].Header, err)
+			if request.Atomic {
+				abort(response, i, 0)
+				return response, nil
+			}
+		}
+	}
+
+	for i, call := range request.Calls {
+		result := response.Results[i]
+		if result.Header.Code != int32(codes.ErrorOK) {
+			continue
+		}
+		// calls after the batch is cancelled don't run
+		err := context.Err()
+		if err != nil {
+			rpc.SetInternalError(result.Header, err)
+			if request.Atomic {
+				context.Rollback()
+				abort(response, i, i)
+				return response, nil
+			}
+			continue
+		}
+		message, err := server.Dispatch(call.Method, call.Body, context)
+		if err == nil {
+			result.Body, err = proto.Marshal(message)
+		}
+		if err != nil {
+			server.Logger.Error("Batched call to method [", call.Method, "] failed - ", err)
+			rpc.SetInternalError(result.Header, err)
+		} else if header := messageHeader(message); header != nil {
+			result.Header = header
+		}
+
+		if request.Atomic && result.Header.Code != int32(codes.ErrorOK) {
+			context.Rollback()
+			abort(response, i, i)
+			return response, nil
+		}
+	}
+	return response, nil
+}
+
+// batchable checks whether a method may be called from a batch.
+// Session only methods act on the signed request itself, so they & batches
+// within batches must be made on their own.
+func batchable(server *rpc.Server, method string, atomic bool) error {
+	if server.FindHandler(method) == nil {
+		server.Logger.Warn("Could not find handler for batched method - ", method)
+		return codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod)
+	}
+	description := server.Methods[method]
+	if method == "Batch" || (description != nil && description.SessionOnly) {
+		server.Logger.Warn("Method [", method, "] can't be batched")
+		return codes.New(codes.ScopeRPC, codes.ErrorInvalidRequest)
+	}
+	if atomic && description != nil && description.Mutating && !description.Reversible {
+		server.Logger.Warn("Method [", method, "] can't be rolled back, so it can't be part of an atomic batch")
+		return codes.New(codes.ScopeRPC, codes.ErrorInvalidRequest)
+	}
+	return nil
+}
+
+// abort reports the failure of an atomic batch.
+// The failed call & the calls that ran before it keep their results, even
+// though their changes have been rolled back.  Every other call is marked as
+// aborted & the batch itself reports the failed call's error.
+func abort(response *messages.BatchResponse, failed int, ran int) {
+	for i := range response.Results {
+		if i < ran || i == failed {
+			continue
+		}
+		response.Results[i] = &messages.BatchResult{Header: rpc.NewResponseHeader()}
+		rpc.SetInternalError(response.Results[i].Header, codes.New(codes.ScopeRPC, codes.ErrorAborted))
+	}
+	response.Header = proto.Clone(response.Results[failed].Header).(*messages.ResponseHeader)
+}
+
+// messageHeader returns the response header of a handler response
+func messageHeader(message proto.Message) *messages.ResponseHeader {
+	type headed interface {
+		GetHeader() *messages.ResponseHeader
+	}
+	if response, ok := message.(headed); ok {
+		return response.GetHeader()
+	}
+	return nil
+}
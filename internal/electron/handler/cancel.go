@@ -0,0 +1,37 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// Cancel cancels a call the calling client still has in flight.
+// The cancelled call stops once its handler notices & responds with
+// ErrorCancelled.
+func Cancel(context *rpc.RequestContext, request *messages.CancelRequest) (*messages.CancelResponse, error) {
+	response := &messages.CancelResponse{
+		Cancelled: context.Token.CancelCall(request.Sequence),
+	}
+	if response.Cancelled {
+		context.Server.Logger.Debug("Cancelled call [", request.Sequence, "]")
+	}
+	return response, nil
+}
@@ -0,0 +1,40 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	"github.com/farrcraft/brewtheory/internal/electron/diag"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// CreateDiagnostics starts a job that writes a diagnostic bundle & returns
+// its id.  The job log reports where the bundle was written.
+func CreateDiagnostics(context *rpc.RequestContext, request *messages.CreateDiagnosticsRequest) (*messages.IdResponse, error) {
+	input := ""
+	if request.ExcludeLogs {
+		input = diag.InputExcludeLogs
+	}
+	job, err := context.Server.Jobs.Start(diag.JobName, input)
+	if err != nil {
+		return nil, err
+	}
+	context.Changed("job", job.ID, nil, jobMessage(job, false))
+	return &messages.IdResponse{Id: job.ID}, nil
+}
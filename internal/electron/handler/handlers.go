@@ -20,10 +20,35 @@ package handler
 
 import "github.com/farrcraft/brewtheory/internal/electron/rpc"
 
-// Handlers returns all available rpc handlers
-func Handlers() map[string]rpc.Handler {
-	handlers := make(map[string]rpc.Handler, 0)
-	handlers["KeyExchange"] = KeyExchange
+// Register registers all available rpc handlers with a server
+func Register(server *rpc.Server) {
+	rpc.Register(server, "KeyExchange", KeyExchange, rpc.SessionOnly(), rpc.Mutating())
+	rpc.Register(server, "Rekey", Rekey, rpc.SessionOnly(), rpc.Mutating())
+	rpc.Register(server, "EndSession", EndSession, rpc.SessionOnly(), rpc.Mutating())
+	rpc.Register(server, "RevokeAll", RevokeAll, rpc.Mutating())
+	rpc.Register(server, "Shutdown", Shutdown, rpc.Mutating())
+	rpc.Register(server, "Batch", Batch, rpc.SessionOnly())
+	rpc.Register(server, "ListMethods", ListMethods)
+	rpc.Register(server, "Cancel", Cancel, rpc.SessionOnly())
+	rpc.Register(server, "StartJob", StartJob, rpc.Mutating())
+	rpc.Register(server, "GetJob", GetJob)
+	rpc.Register(server, "ListJobs", ListJobs)
+	rpc.Register(server, "CancelJob", CancelJob, rpc.Mutating())
+	rpc.Register(server, "RetryJob", RetryJob, rpc.Mutating())
+	rpc.Register(server, "ServerStats", ServerStats)
+	rpc.Register(server, "QueryAudit", QueryAudit)
+	rpc.Register(server, "GetLogLevels", GetLogLevels)
+	rpc.Register(server, "SetLogLevel", SetLogLevel, rpc.Mutating(), rpc.Reversible())
+	rpc.Register(server, "CreateDiagnostics", CreateDiagnostics, rpc.Mutating())
+}
+
+// Policies returns the authorization policies for rpc handlers.
+// No method needs rpc.RequireToken: "/rpc" only dispatches calls that
+// authenticate with a session after a key exchange & the JSON gateway only
+// dispatches calls carrying a valid API token.  Policies are for rules
+// beyond being authenticated.
+func Policies() map[string]rpc.Policy {
+	policies := make(map[string]rpc.Policy, 0)
 
-	return handlers
+	return policies
 }
@@ -0,0 +1,121 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	"time"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	"github.com/farrcraft/brewtheory/internal/electron/jobs"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// TopicJobs is the event topic job updates are published to
+const TopicJobs = "jobs"
+
+// StartJob queues a named job & returns its id
+func StartJob(context *rpc.RequestContext, request *messages.StartJobRequest) (*messages.IdResponse, error) {
+	job, err := context.Server.Jobs.Start(request.Name, request.Input)
+	if err != nil {
+		return nil, err
+	}
+	context.Changed("job", job.ID, nil, jobMessage(job, false))
+	return &messages.IdResponse{Id: job.ID}, nil
+}
+
+// GetJob returns a job along with its log
+func GetJob(context *rpc.RequestContext, request *messages.IdRequest) (*messages.JobResponse, error) {
+	job, ok := context.Server.Jobs.Get(request.Id)
+	if !ok {
+		return nil, codes.NewApplication(codes.ScopeGeneral, codes.ErrorRecordMissing).WithEntity("job", request.Id)
+	}
+	return &messages.JobResponse{Job: jobMessage(job, true)}, nil
+}
+
+// ListJobs returns every job, oldest first, without their logs
+func ListJobs(context *rpc.RequestContext, request *messages.EmptyRequest) (*messages.ListJobsResponse, error) {
+	response := &messages.ListJobsResponse{}
+	for _, job := range context.Server.Jobs.List() {
+		response.Jobs = append(response.Jobs, jobMessage(job, false))
+	}
+	return response, nil
+}
+
+// CancelJob asks an active job to stop.
+// The job reports that it was cancelled once it has stopped.
+func CancelJob(context *rpc.RequestContext, request *messages.IdRequest) (*messages.IdResponse, error) {
+	before, _ := context.Server.Jobs.Get(request.Id)
+	cancelled, err := context.Server.Jobs.Cancel(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	if cancelled {
+		after, _ := context.Server.Jobs.Get(request.Id)
+		context.Changed("job", request.Id, jobMessage(before, false), jobMessage(after, false))
+	}
+	return &messages.IdResponse{Id: request.Id}, nil
+}
+
+// RetryJob starts a failed or cancelled job again
+func RetryJob(context *rpc.RequestContext, request *messages.IdRequest) (*messages.IdResponse, error) {
+	before, _ := context.Server.Jobs.Get(request.Id)
+	job, err := context.Server.Jobs.Retry(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	context.Changed("job", job.ID, jobMessage(before, false), jobMessage(job, false))
+	return &messages.IdResponse{Id: job.ID}, nil
+}
+
+// PublishJobs returns a job update callback that publishes jobs, without
+// their logs, on the event bus
+func PublishJobs(server *rpc.Server) func(jobs.Job) {
+	return func(job jobs.Job) {
+		server.Events.Publish(TopicJobs, jobMessage(job, false))
+	}
+}
+
+// jobMessage converts a job into its RPC message
+func jobMessage(job jobs.Job, withLog bool) *messages.Job {
+	message := &messages.Job{
+		Id:         job.ID,
+		Name:       job.Name,
+		Input:      job.Input,
+		Status:     string(job.Status),
+		Progress:   job.Progress,
+		Error:      job.Error,
+		Attempts:   job.Attempts,
+		CreatedAt:  unixMilli(job.CreatedAt),
+		StartedAt:  unixMilli(job.StartedAt),
+		FinishedAt: unixMilli(job.FinishedAt),
+	}
+	if withLog {
+		message.Log = job.Log
+	}
+	return message
+}
+
+// unixMilli converts a time to unix milliseconds, keeping zero times zero
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
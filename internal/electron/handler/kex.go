@@ -19,52 +19,77 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package handler
 
 import (
-	"google.golang.org/protobuf/proto"
-
 	"github.com/farrcraft/brewtheory/internal/electron/codes"
 	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
 	"github.com/farrcraft/brewtheory/internal/electron/rpc"
 )
 
 // KeyExchange performs a key exchange between client & server
-func KeyExchange(server *rpc.Server, message []byte, context *rpc.RequestContext) (proto.Message, error) {
-	response := &messages.KeyExchangeResponse{
-		Header: rpc.NewResponseHeader(),
+func KeyExchange(context *rpc.RequestContext, request *messages.KeyExchangeRequest) (*messages.KeyExchangeResponse, error) {
+	server := context.Server
+
+	// incompatible clients are refused before they can claim the pairing
+	version, capabilities, err := server.Negotiate(request.ProtocolVersion, request.Capabilities)
+	if err != nil {
+		return nil, err
 	}
 
-	request := messages.KeyExchangeRequest{}
-	err := proto.Unmarshal(message, &request)
+	// only the process that launched us knows the bootstrap secret
+	err = server.Pairing.Claim(request.PublicKey, request.BootstrapProof)
 	if err != nil {
-		server.Logger.Warn("Error unmarshaling message - ", err)
-		rpc.SetRPCError(response.Header, codes.ErrorDecode)
-		return response, nil
+		return nil, err
 	}
+	// the pairing is only used up once the session exists
+	paired := false
+	defer func() {
+		if !paired {
+			server.Pairing.Release()
+		}
+	}()
+	server.Logger.Info("Client app version [", request.AppVersion, "] speaks protocol version [", version, "] with capabilities ", capabilities)
 
 	// create a new client token
-	context.Token, err = rpc.NewClientToken(server.Logger)
+	token, err := rpc.NewClientToken(server.Logger)
 	if err != nil {
-		rpc.SetRPCError(response.Header, codes.ErrorCrypto)
-		return response, nil
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorCrypto)
+	}
+	context.Token = token
+	context.Token.ProtocolVersion = version
+	context.Token.AppVersion = request.AppVersion
+	context.Token.Capabilities = make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		context.Token.Capabilities[capability] = true
 	}
-	// [FIXME] - assert request key length matches our target array size
 
 	// client sent its own public key so we can verify requests it sends us later
-	// this is a bit weak sauce wrt security since signature & verification key
-	// are contained in the same message body, but it does give us assurance
-	// that we at least have a functional verification key.
+	// the server has already checked this request was signed with it, but
+	// the signature & key come in the same message body, so it's the bootstrap
+	// proof that ties the key to the launching process.
 	context.Token.VerifyPublicKey = make([]byte, len(request.PublicKey))
 	copy(context.Token.VerifyPublicKey, request.PublicKey)
 
-	// send our own public key so client can verify our responses
-	response.PublicKey = context.Token.SignPublicKey[:]
-	// the client will also need to keep track of its identifying token for future requests
-	response.Token = context.Token.Token
+	response := &messages.KeyExchangeResponse{
+		// send our own public key so client can verify our responses
+		PublicKey: context.Token.SignPublicKey[:],
+		// the client will also need to keep track of its identifying token for future requests
+		Token: context.Token.Token,
+		// let the client know what it can rely on for this session
+		ProtocolVersion: version,
+		AppVersion:      server.AppVersion,
+		Capabilities:    capabilities,
+	}
 
 	// reset sequence counters
 	context.Token.SendCounter = 0
-	context.Token.RecvCounter = 1
+	context.Token.RecvWindow.Reset(1)
 
-	server.Clients[context.Token.Token] = context.Token
+	err = server.Sessions.Add(context.Token)
+	if err != nil {
+		context.Token = nil
+		return nil, err
+	}
+	server.Pairing.Commit()
+	paired = true
 
 	return response, nil
 }
@@ -0,0 +1,83 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// GetLogLevels returns the level of every logger
+func GetLogLevels(context *rpc.RequestContext, request *messages.EmptyRequest) (*messages.LogLevelsResponse, error) {
+	if context.Server.Logs == nil {
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod)
+	}
+	return logLevels(context), nil
+}
+
+// SetLogLevel changes the level of a logger without restarting the service.
+// The change lasts until the service stops or the atomic batch it is part of
+// fails.
+func SetLogLevel(context *rpc.RequestContext, request *messages.SetLogLevelRequest) (*messages.LogLevelsResponse, error) {
+	if context.Server.Logs == nil {
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod)
+	}
+	level, err := logrus.ParseLevel(request.Level)
+	if err != nil {
+		return nil, codes.NewApplication(codes.ScopeRPC, codes.ErrorInvalidRequest).WithField("level", "invalid", "unknown log level")
+	}
+	before := logLevels(context)
+	previous := context.Server.Logs.Levels()
+	if !context.Server.Logs.SetLevel(request.Subsystem, level) {
+		return nil, codes.NewApplication(codes.ScopeRPC, codes.ErrorInvalidRequest).WithField("subsystem", "unknown", "unknown log subsystem")
+	}
+	context.OnRollback(func() {
+		for _, level := range previous {
+			context.Server.Logs.SetLevel(level.Name, level.Level)
+		}
+	})
+	context.Server.Logger.Info("Log level of [", subsystemName(request.Subsystem), "] set to [", level, "]")
+
+	response := logLevels(context)
+	context.Changed("log_level", subsystemName(request.Subsystem), before, response)
+	return response, nil
+}
+
+// logLevels lists the level of every logger
+func logLevels(context *rpc.RequestContext) *messages.LogLevelsResponse {
+	response := &messages.LogLevelsResponse{}
+	for _, level := range context.Server.Logs.Levels() {
+		response.Levels = append(response.Levels, &messages.LogLevel{
+			Subsystem: level.Name,
+			Level:     level.Level.String(),
+		})
+	}
+	return response
+}
+
+// subsystemName names the loggers a level change applies to
+func subsystemName(subsystem string) string {
+	if subsystem == "" {
+		return "all"
+	}
+	return subsystem
+}
@@ -0,0 +1,66 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package handler
+
+import (
+	"sort"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// Rekey replaces the keys of the calling client's session.
+// The request is verified with the current keys & the response is signed
+// with the new ones.  Both sequences restart so the next request the client
+// sends is sequence 2.  Open event streams are closed since they were signed
+// with the old keys.
+func Rekey(context *rpc.RequestContext, request *messages.KeyExchangeRequest) (*messages.KeyExchangeResponse, error) {
+	err := context.Token.Rekey(request.PublicKey, context.Server.Logger)
+	if err != nil {
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorCrypto)
+	}
+	context.Server.Events.CloseToken(context.Token)
+
+	response := &messages.KeyExchangeResponse{
+		PublicKey: context.Token.PublicKey(),
+		Token:     context.Token.Token,
+		// the session keeps what was negotiated by its key exchange
+		ProtocolVersion: context.Token.ProtocolVersion,
+		AppVersion:      context.Server.AppVersion,
+	}
+	for capability := range context.Token.Capabilities {
+		response.Capabilities = append(response.Capabilities, capability)
+	}
+	sort.Strings(response.Capabilities)
+	return response, nil
+}
+
+// EndSession ends the calling client's session.
+// The response is still signed with the session keys.
+func EndSession(context *rpc.RequestContext, request *messages.EmptyRequest) (*messages.EmptyResponse, error) {
+	context.Server.Sessions.End(context.Token.Token)
+	return &messages.EmptyResponse{}, nil
+}
+
+// RevokeAll ends every client session, including the caller's
+func RevokeAll(context *rpc.RequestContext, request *messages.EmptyRequest) (*messages.EmptyResponse, error) {
+	count := context.Server.Sessions.RevokeAll()
+	context.Server.Logger.Info("Revoked [", count, "] sessions")
+	return &messages.EmptyResponse{}, nil
+}
@@ -0,0 +1,33 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// Shutdown asks the backend to shut down gracefully.
+// The response is sent before the server begins draining requests.
+func Shutdown(context *rpc.RequestContext, request *messages.EmptyRequest) (*messages.EmptyResponse, error) {
+	context.Server.Logger.Info("Shutdown requested by client")
+	context.Server.RequestShutdown(true)
+
+	return &messages.EmptyResponse{}, nil
+}
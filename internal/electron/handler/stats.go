@@ -0,0 +1,40 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package handler
+
+import (
+	"sort"
+
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// ServerStats reports session & rejected request counters, sorted by name
+func ServerStats(context *rpc.RequestContext, request *messages.EmptyRequest) (*messages.ServerStatsResponse, error) {
+	response := &messages.ServerStatsResponse{
+		Sessions: int32(context.Server.Sessions.Count()),
+	}
+	for reason, count := range context.Server.Rejections.Counts() {
+		response.Rejected = append(response.Rejected, &messages.Counter{Name: reason, Value: count})
+	}
+	sort.Slice(response.Rejected, func(i, j int) bool {
+		return response.Rejected[i].Name < response.Rejected[j].Name
+	})
+	return response, nil
+}
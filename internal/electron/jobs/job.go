@@ -0,0 +1,110 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Status is the state of a job
+type Status string
+
+// Job states.  Queued & running jobs are active, the others are finished.
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// MaxLogLines is the number of log lines kept for a job, oldest first out
+const MaxLogLines = 200
+
+// Job is a unit of work that runs in the background
+type Job struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Input is passed to the runner, e.g. the path of a file to import
+	Input    string   `json:"input,omitempty"`
+	Status   Status   `json:"status"`
+	Progress int32    `json:"progress"`
+	Log      []string `json:"log,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	// Attempts counts how many times the job has been started
+	Attempts   int32     `json:"attempts"`
+	CreatedAt  time.Time `json:"createdAt"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Active checks whether a job is queued or running
+func (job *Job) Active() bool {
+	return job.Status == StatusQueued || job.Status == StatusRunning
+}
+
+// copy returns a snapshot of the job that is safe to hand out
+func (job *Job) copy() Job {
+	snapshot := *job
+	snapshot.Log = append([]string(nil), job.Log...)
+	return snapshot
+}
+
+// Runner does the work of a named job.
+// It should report progress through run & return once ctx is done.
+type Runner func(ctx context.Context, run *Run) error
+
+// Run is a runner's handle on the job it is running
+type Run struct {
+	ID      string
+	Input   string
+	Attempt int32
+
+	manager *Manager
+}
+
+// Progress reports how far through the job is, as a percentage
+func (run *Run) Progress(percent int32) {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	run.manager.update(run.ID, func(job *Job) bool {
+		if job.Progress == percent {
+			return false
+		}
+		job.Progress = percent
+		return true
+	})
+}
+
+// Logf adds a line to the job log
+func (run *Run) Logf(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	run.manager.update(run.ID, func(job *Job) bool {
+		job.Log = append(job.Log, line)
+		if len(job.Log) > MaxLogLines {
+			job.Log = job.Log[len(job.Log)-MaxLogLines:]
+		}
+		return true
+	})
+}
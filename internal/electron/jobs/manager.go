@@ -0,0 +1,474 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// DefaultMaxRunning is the number of jobs that may run at the same time.
+// Other jobs wait in the queue.
+const DefaultMaxRunning = 2
+
+// DefaultMaxFinished is the number of finished jobs kept in the history
+const DefaultMaxFinished = 100
+
+// DefaultSaveDelay is how long progress & log updates are collected before
+// the jobs are persisted
+const DefaultSaveDelay = time.Second
+
+// Manager runs named jobs in the background & persists their status so it
+// survives restarts.  Jobs that were active when the service stopped are
+// reported as failed the next time it starts & can be retried.
+type Manager struct {
+	Logger *logrus.Logger
+	// OnUpdate is called with a snapshot of a job whenever it changes.
+	// It is called with the manager locked so updates arrive in order, so it
+	// must not block or call back into the manager.
+	OnUpdate func(Job)
+	// MaxFinished bounds how many finished jobs are kept
+	MaxFinished int
+	// SaveDelay batches progress & log updates into a single write.  Jobs
+	// are saved straight away when they start or finish.
+	SaveDelay time.Duration
+
+	path    string
+	mutex   sync.Mutex
+	jobs    map[string]*Job
+	runners map[string]Runner
+	cancels map[string]context.CancelFunc
+	slots   chan struct{}
+	running sync.WaitGroup
+	closed  bool
+	// a save is pending for updates that haven't been persisted yet
+	dirty     bool
+	saveTimer *time.Timer
+}
+
+// NewManager creates a job manager that persists jobs to a JSON file.
+// A history that can't be read is logged & replaced.
+func NewManager(logger *logrus.Logger, path string, maxRunning int) *Manager {
+	if maxRunning < 1 {
+		maxRunning = DefaultMaxRunning
+	}
+	manager := &Manager{
+		Logger:      logger,
+		MaxFinished: DefaultMaxFinished,
+		SaveDelay:   DefaultSaveDelay,
+		path:        path,
+		jobs:        make(map[string]*Job),
+		runners:     make(map[string]Runner),
+		cancels:     make(map[string]context.CancelFunc),
+		slots:       make(chan struct{}, maxRunning),
+	}
+	err := manager.load()
+	if err != nil {
+		logger.Warn("Error loading job history - ", err)
+	}
+	return manager
+}
+
+// Register makes a named job available to be started
+func (manager *Manager) Register(name string, runner Runner) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.runners[name] = runner
+}
+
+// Start queues a new job
+func (manager *Manager) Start(name string, input string) (Job, error) {
+	manager.mutex.Lock()
+	if manager.closed {
+		manager.mutex.Unlock()
+		manager.Logger.Warn("Job [", name, "] not started, shutting down")
+		return Job{}, codes.New(codes.ScopeGeneral, codes.ErrorInvalidRequest)
+	}
+	runner, ok := manager.runners[name]
+	if !ok {
+		manager.mutex.Unlock()
+		manager.Logger.Warn("Unknown job [", name, "]")
+		return Job{}, codes.NewApplication(codes.ScopeGeneral, codes.ErrorUnknownJob).WithField("name", "unknown", "no job is registered with this name")
+	}
+	id, err := newID()
+	if err != nil {
+		manager.mutex.Unlock()
+		manager.Logger.Warn("Error creating job id - ", err)
+		return Job{}, codes.Wrap(codes.ScopeGeneral, codes.ErrorCreate, err)
+	}
+	job := &Job{
+		ID:        id,
+		Name:      name,
+		Input:     input,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+	}
+	manager.jobs[id] = job
+	manager.prune()
+	manager.launch(job, runner)
+	snapshot := job.copy()
+	manager.save()
+	manager.notify(snapshot)
+	manager.mutex.Unlock()
+
+	manager.Logger.Info("Started job [", name, "] as [", id, "]")
+	return snapshot, nil
+}
+
+// Get returns a snapshot of a job
+func (manager *Manager) Get(id string) (Job, bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	job, ok := manager.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.copy(), true
+}
+
+// List returns a snapshot of every job, oldest first
+func (manager *Manager) List() []Job {
+	manager.mutex.Lock()
+	list := make([]Job, 0, len(manager.jobs))
+	for _, job := range manager.jobs {
+		list = append(list, job.copy())
+	}
+	manager.mutex.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Cancel asks an active job to stop.
+// It reports false if the job had already finished.
+func (manager *Manager) Cancel(id string) (bool, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if _, ok := manager.jobs[id]; !ok {
+		return false, codes.NewApplication(codes.ScopeGeneral, codes.ErrorRecordMissing).WithEntity("job", id)
+	}
+	cancel, ok := manager.cancels[id]
+	if !ok {
+		return false, nil
+	}
+	manager.Logger.Info("Cancelling job [", id, "]")
+	cancel()
+	return true, nil
+}
+
+// Retry starts a failed or cancelled job again with the same input
+func (manager *Manager) Retry(id string) (Job, error) {
+	manager.mutex.Lock()
+	job, ok := manager.jobs[id]
+	if !ok {
+		manager.mutex.Unlock()
+		return Job{}, codes.NewApplication(codes.ScopeGeneral, codes.ErrorRecordMissing).WithEntity("job", id)
+	}
+	if manager.closed || (job.Status != StatusFailed && job.Status != StatusCancelled) {
+		manager.mutex.Unlock()
+		manager.Logger.Warn("Job [", id, "] can't be retried while ", job.Status)
+		return Job{}, codes.NewApplication(codes.ScopeGeneral, codes.ErrorInvalidRequest).WithEntity("job", id)
+	}
+	runner, ok := manager.runners[job.Name]
+	if !ok {
+		manager.mutex.Unlock()
+		manager.Logger.Warn("Unknown job [", job.Name, "]")
+		return Job{}, codes.New(codes.ScopeGeneral, codes.ErrorUnknownJob)
+	}
+	job.Status = StatusQueued
+	job.Progress = 0
+	job.Error = ""
+	job.StartedAt = time.Time{}
+	job.FinishedAt = time.Time{}
+	manager.launch(job, runner)
+	job.Log = append(job.Log, fmt.Sprint("retrying, attempt ", job.Attempts))
+	snapshot := job.copy()
+	manager.save()
+	manager.notify(snapshot)
+	manager.mutex.Unlock()
+
+	manager.Logger.Info("Retrying job [", id, "]")
+	return snapshot, nil
+}
+
+// Close cancels every active job & waits for them to stop or for ctx to
+// expire.  No jobs can be started afterwards.
+func (manager *Manager) Close(ctx context.Context) error {
+	manager.mutex.Lock()
+	manager.closed = true
+	for _, cancel := range manager.cancels {
+		cancel()
+	}
+	manager.mutex.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		manager.running.Wait()
+		close(stopped)
+	}()
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// don't lose updates that were waiting to be saved
+	manager.mutex.Lock()
+	if manager.saveTimer != nil {
+		manager.saveTimer.Stop()
+		manager.saveTimer = nil
+	}
+	if manager.dirty {
+		manager.save()
+	}
+	manager.mutex.Unlock()
+	return err
+}
+
+// launch starts the goroutine that runs a queued job.
+// The manager must be locked.
+func (manager *Manager) launch(job *Job, runner Runner) {
+	ctx, cancel := context.WithCancel(context.Background())
+	manager.cancels[job.ID] = cancel
+	job.Attempts++
+	run := &Run{
+		ID:      job.ID,
+		Input:   job.Input,
+		Attempt: job.Attempts,
+		manager: manager,
+	}
+	manager.running.Add(1)
+	go manager.run(ctx, runner, run)
+}
+
+// run waits for a free slot then runs a job to completion
+func (manager *Manager) run(ctx context.Context, runner Runner, run *Run) {
+	defer manager.running.Done()
+
+	select {
+	case manager.slots <- struct{}{}:
+		defer func() { <-manager.slots }()
+	case <-ctx.Done():
+		manager.finish(ctx, run.ID, ctx.Err())
+		return
+	}
+
+	manager.update(run.ID, func(job *Job) bool {
+		job.Status = StatusRunning
+		job.StartedAt = time.Now()
+		return true
+	})
+	err := call(ctx, runner, run)
+	manager.finish(ctx, run.ID, err)
+}
+
+// call runs a runner, turning a panic into an error
+func call(ctx context.Context, runner Runner, run *Run) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked - %v", r)
+		}
+	}()
+	return runner(ctx, run)
+}
+
+// finish records how a job ended
+func (manager *Manager) finish(ctx context.Context, id string, err error) {
+	// checked before the job's own cancel func releases the context
+	cancelled := ctx.Err() != nil
+
+	manager.mutex.Lock()
+	if cancel, ok := manager.cancels[id]; ok {
+		cancel()
+		delete(manager.cancels, id)
+	}
+	job, ok := manager.jobs[id]
+	if !ok {
+		manager.mutex.Unlock()
+		return
+	}
+	switch {
+	case err == nil:
+		job.Status = StatusSucceeded
+		job.Progress = 100
+	case cancelled:
+		job.Status = StatusCancelled
+	default:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+	job.FinishedAt = time.Now()
+	snapshot := job.copy()
+	manager.save()
+	manager.notify(snapshot)
+	manager.mutex.Unlock()
+
+	if snapshot.Status == StatusFailed {
+		manager.Logger.Warn("Job [", id, "] failed - ", err)
+	} else {
+		manager.Logger.Info("Job [", id, "] ", snapshot.Status)
+	}
+}
+
+// update changes a job & schedules a save if fn reports a change
+func (manager *Manager) update(id string, fn func(*Job) bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	job, ok := manager.jobs[id]
+	if !ok || !fn(job) {
+		return
+	}
+	manager.saveLater()
+	manager.notify(job.copy())
+}
+
+// notify reports a change to a job.
+// The manager must be locked.
+func (manager *Manager) notify(job Job) {
+	if manager.OnUpdate != nil {
+		manager.OnUpdate(job)
+	}
+}
+
+// prune drops the oldest finished jobs beyond MaxFinished.
+// The manager must be locked.
+func (manager *Manager) prune() {
+	var finished []*Job
+	for _, job := range manager.jobs {
+		if !job.Active() {
+			finished = append(finished, job)
+		}
+	}
+	if manager.MaxFinished <= 0 || len(finished) <= manager.MaxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+	})
+	for _, job := range finished[:len(finished)-manager.MaxFinished] {
+		delete(manager.jobs, job.ID)
+	}
+}
+
+// ReadFile reads persisted jobs without changing them, oldest first.
+// A missing file has no jobs.
+func ReadFile(path string) ([]*Job, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading jobs - %w", err)
+	}
+	var saved []*Job
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding jobs - %w", err)
+	}
+	return saved, nil
+}
+
+// load reads the persisted jobs.
+// Jobs that were active when the service stopped were interrupted.
+func (manager *Manager) load() error {
+	saved, err := ReadFile(manager.path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, job := range saved {
+		if job.Active() {
+			job.Status = StatusFailed
+			job.Error = "interrupted by service shutdown"
+			job.FinishedAt = now
+		}
+		manager.jobs[job.ID] = job
+	}
+	return nil
+}
+
+// saveLater persists every job once SaveDelay has passed, so a burst of
+// updates is written once.
+// The manager must be locked.
+func (manager *Manager) saveLater() {
+	manager.dirty = true
+	if manager.saveTimer != nil || manager.closed {
+		return
+	}
+	manager.saveTimer = time.AfterFunc(manager.SaveDelay, func() {
+		manager.mutex.Lock()
+		defer manager.mutex.Unlock()
+		manager.saveTimer = nil
+		if manager.dirty {
+			manager.save()
+		}
+	})
+}
+
+// save persists every job.  The file is replaced atomically so a crash
+// can't leave it half written.
+// The manager must be locked.
+func (manager *Manager) save() {
+	manager.dirty = false
+	list := make([]*Job, 0, len(manager.jobs))
+	for _, job := range manager.jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		manager.Logger.Warn("Error encoding jobs - ", err)
+		return
+	}
+	temp := manager.path + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err == nil {
+		err = os.Rename(temp, manager.path)
+	}
+	if err != nil {
+		manager.Logger.Warn("Error saving jobs - ", err)
+	}
+}
+
+// newID creates a random job id
+func newID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,63 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package electron
+
+import (
+	"context"
+)
+
+// ShutdownHook is called during service shutdown so a subsystem can flush
+// its state.  The context expires when the shutdown deadline is reached.
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// OnShutdown registers a hook to run during shutdown.
+// Hooks run after the RPC server has drained, in the reverse order of their
+// registration, so a subsystem registered after its dependencies is shut
+// down before them.
+func (service *Electron) OnShutdown(name string, hook ShutdownHook) {
+	service.hooks = append(service.hooks, shutdownHook{name: name, hook: hook})
+}
+
+// shutdown stops the RPC server & runs the shutdown hooks
+// It returns false if anything failed to shut down cleanly.
+func (service *Electron) shutdown() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), service.ShutdownTimeout)
+	defer cancel()
+
+	ok := true
+	if service.RPC != nil && !service.RPC.Stop(ctx) {
+		ok = false
+	}
+
+	for i := len(service.hooks) - 1; i >= 0; i-- {
+		hook := service.hooks[i]
+		service.Logger.Debug("Running shutdown hook [", hook.name, "]")
+		err := hook.hook(ctx)
+		if err != nil {
+			service.Logger.Error("Shutdown hook [", hook.name, "] failed - ", err)
+			ok = false
+		}
+	}
+	return ok
+}
@@ -0,0 +1,198 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Root names the service's main logger
+const Root = "service"
+
+// Subsystems are the named loggers whose levels can be set independently.
+// The db, sensors & calc loggers are registered ahead of those subsystems so
+// their levels can already be configured.
+var Subsystems = []string{"rpc", "jobs", "diag", "db", "sensors", "calc"}
+
+// Options configure where the service logs & how verbosely
+type Options struct {
+	// Level is the level of every logger that isn't given one in Levels
+	Level string
+	// Levels holds per logger levels, keyed by subsystem name
+	Levels map[string]string
+	// File is the log file path
+	File       string
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+}
+
+// DefaultOptions logs at debug level to a rotated file
+var DefaultOptions = Options{
+	Level:      "DEBUG",
+	MaxSize:    DefaultMaxSize,
+	MaxAge:     DefaultMaxAge,
+	MaxBackups: DefaultMaxBackups,
+}
+
+// ParseLevels parses "subsystem=level" pairs into a map of levels
+func ParseLevels(values []string) (map[string]string, error) {
+	levels := make(map[string]string, len(values))
+	for _, value := range values {
+		name, level, ok := strings.Cut(value, "=")
+		if !ok || name == "" || level == "" {
+			return nil, fmt.Errorf("log level [%s] must be given as subsystem=level", value)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// Loggers is the set of named loggers the service writes to.
+// Every logger shares the same output & redaction but has its own level.
+type Loggers struct {
+	Out io.Writer
+
+	mutex   sync.Mutex
+	loggers map[string]*logrus.Logger
+}
+
+// New creates the root logger & a logger for each subsystem writing to out
+func New(out io.Writer, options Options) (*Loggers, error) {
+	level, err := logrus.ParseLevel(options.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level [%s]", options.Level)
+	}
+	loggers := &Loggers{
+		Out:     out,
+		loggers: make(map[string]*logrus.Logger, len(Subsystems)+1),
+	}
+	loggers.loggers[Root] = loggers.newLogger("", level)
+	for _, name := range Subsystems {
+		loggers.loggers[name] = loggers.newLogger(name, level)
+	}
+	for name, value := range options.Levels {
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level [%s] for [%s]", value, name)
+		}
+		if !loggers.SetLevel(name, level) {
+			return nil, fmt.Errorf("unknown log subsystem [%s]", name)
+		}
+	}
+	return loggers, nil
+}
+
+// newLogger creates a logger tagging its entries with a subsystem name
+func (loggers *Loggers) newLogger(subsystem string, level logrus.Level) *logrus.Logger {
+	logger := logrus.New()
+	logger.Formatter = &logrus.JSONFormatter{}
+	logger.Out = loggers.Out
+	logger.Level = level
+	if subsystem != "" {
+		logger.AddHook(&subsystemHook{name: subsystem})
+	}
+	logger.AddHook(NewRedactHook())
+	return logger
+}
+
+// Root returns the service's main logger
+func (loggers *Loggers) Root() *logrus.Logger {
+	return loggers.Get(Root)
+}
+
+// Get returns a named logger, or the root logger for unknown names
+func (loggers *Loggers) Get(name string) *logrus.Logger {
+	loggers.mutex.Lock()
+	defer loggers.mutex.Unlock()
+	logger, ok := loggers.loggers[name]
+	if !ok {
+		return loggers.loggers[Root]
+	}
+	return logger
+}
+
+// SetLevel changes the level of a named logger.  An empty name changes
+// every logger.  It reports false for unknown names.
+func (loggers *Loggers) SetLevel(name string, level logrus.Level) bool {
+	loggers.mutex.Lock()
+	defer loggers.mutex.Unlock()
+	if name == "" {
+		for _, logger := range loggers.loggers {
+			logger.SetLevel(level)
+		}
+		return true
+	}
+	logger, ok := loggers.loggers[name]
+	if !ok {
+		return false
+	}
+	logger.SetLevel(level)
+	return true
+}
+
+// Levels returns the level of every logger, sorted by name
+func (loggers *Loggers) Levels() []Level {
+	loggers.mutex.Lock()
+	defer loggers.mutex.Unlock()
+	levels := make([]Level, 0, len(loggers.loggers))
+	for name, logger := range loggers.loggers {
+		levels = append(levels, Level{Name: name, Level: logger.GetLevel()})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Name < levels[j].Name
+	})
+	return levels
+}
+
+// Close closes the shared output if it can be closed
+func (loggers *Loggers) Close() error {
+	closer, ok := loggers.Out.(io.Closer)
+	if !ok {
+		return nil
+	}
+	return closer.Close()
+}
+
+// Level is the level a named logger is writing at
+type Level struct {
+	Name  string
+	Level logrus.Level
+}
+
+// subsystemHook tags entries with the subsystem that logged them
+type subsystemHook struct {
+	name string
+}
+
+func (hook *subsystemHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *subsystemHook) Fire(entry *logrus.Entry) error {
+	entry.Data["subsystem"] = hook.name
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package logging
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces sensitive values in log entries
+const Redacted = "[REDACTED]"
+
+// DefaultSensitiveFields are the parts of entry field names that mark their
+// values as sensitive
+var DefaultSensitiveFields = []string{"token", "key", "secret", "signature", "password", "authorization"}
+
+// sensitiveText matches secrets that can appear in log messages, such as
+// API tokens & bearer credentials
+var sensitiveText = regexp.MustCompile(`(?i)\bbt_[A-Za-z0-9_\-]+|(\bbearer\s+)\S+`)
+
+// RedactHook is a logrus hook that blanks sensitive fields & secrets in
+// messages before an entry is written.  Field names are matched case
+// insensitively on any part of the name, so "clientToken" & "signKey" are
+// both redacted.
+type RedactHook struct {
+	Fields []string
+}
+
+// NewRedactHook creates a hook redacting the default sensitive fields
+func NewRedactHook() *RedactHook {
+	return &RedactHook{Fields: DefaultSensitiveFields}
+}
+
+// Levels is part of the logrus.Hook interface
+func (hook *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire is part of the logrus.Hook interface
+func (hook *RedactHook) Fire(entry *logrus.Entry) error {
+	for name := range entry.Data {
+		if hook.sensitive(name) {
+			entry.Data[name] = Redacted
+		}
+	}
+	entry.Message = redactText(entry.Message)
+	return nil
+}
+
+// RedactLine redacts a line that has already been logged, such as when logs
+// are copied into a diagnostic bundle.  JSON entries have their sensitive
+// fields blanked & every line has secrets removed from its text.
+func (hook *RedactHook) RedactLine(line []byte) []byte {
+	var fields map[string]interface{}
+	if json.Unmarshal(line, &fields) != nil {
+		return []byte(redactText(string(line)))
+	}
+	for name, value := range fields {
+		if hook.sensitive(name) {
+			fields[name] = Redacted
+		} else if text, ok := value.(string); ok {
+			fields[name] = redactText(text)
+		}
+	}
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return []byte(redactText(string(line)))
+	}
+	return redacted
+}
+
+// redactText removes API tokens & bearer credentials from text
+func redactText(text string) string {
+	return sensitiveText.ReplaceAllStringFunc(text, func(match string) string {
+		if strings.HasPrefix(strings.ToLower(match), "bt_") {
+			return Redacted
+		}
+		return sensitiveText.ReplaceAllString(match, "${1}"+Redacted)
+	})
+}
+
+func (hook *RedactHook) sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range hook.Fields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
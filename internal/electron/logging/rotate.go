@@ -0,0 +1,196 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for log rotation
+const (
+	DefaultMaxSize    = 10 << 20
+	DefaultMaxAge     = 24 * time.Hour
+	DefaultMaxBackups = 5
+)
+
+// backupTimeFormat is the timestamp added to the names of rotated files
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile is a log file that is moved aside & replaced once it grows
+// too large or has been open too long.  Only the newest backups are kept.
+// It is safe for concurrent use, so several loggers can share one.
+type RotatingFile struct {
+	Path string
+	// MaxSize is the largest the file may grow in bytes, 0 for no limit
+	MaxSize int64
+	// MaxAge is how long a file is written to before it is rotated, 0 for no
+	// limit
+	MaxAge time.Duration
+	// MaxBackups is how many rotated files are kept, 0 keeps them all
+	MaxBackups int
+
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// OpenRotatingFile opens a log file for appending, creating its directory if
+// needed
+func OpenRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	file := &RotatingFile{
+		Path:       path,
+		MaxSize:    maxSize,
+		MaxAge:     maxAge,
+		MaxBackups: maxBackups,
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("error creating log directory - %w", err)
+	}
+	err = file.open()
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Write appends to the log file, rotating it first if the write would take
+// it over its size limit or it is too old
+func (file *RotatingFile) Write(p []byte) (int, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.file == nil {
+		return 0, os.ErrClosed
+	}
+	tooLarge := file.MaxSize > 0 && file.size > 0 && file.size+int64(len(p)) > file.MaxSize
+	tooOld := file.MaxAge > 0 && time.Since(file.opened) > file.MaxAge
+	if tooLarge || tooOld {
+		err := file.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := file.file.Write(p)
+	file.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current file aside & starts a new one
+func (file *RotatingFile) Rotate() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	if file.file == nil {
+		return os.ErrClosed
+	}
+	return file.rotate()
+}
+
+// Close closes the log file
+func (file *RotatingFile) Close() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	if file.file == nil {
+		return nil
+	}
+	err := file.file.Close()
+	file.file = nil
+	return err
+}
+
+// Backups returns the paths of the rotated files, oldest first
+func (file *RotatingFile) Backups() ([]string, error) {
+	ext := filepath.Ext(file.Path)
+	prefix := strings.TrimSuffix(file.Path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	// the timestamps sort in the order the files were rotated
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func (file *RotatingFile) open() error {
+	f, err := os.OpenFile(file.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return fmt.Errorf("error opening log file - %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error opening log file - %w", err)
+	}
+	file.file = f
+	file.size = info.Size()
+	// an existing file is aged from when it was last modified, so one left
+	// idle past the max age is rotated by the first write after a restart
+	file.opened = time.Now()
+	if info.Size() > 0 {
+		file.opened = info.ModTime()
+	}
+	return nil
+}
+
+func (file *RotatingFile) rotate() error {
+	err := file.file.Close()
+	file.file = nil
+	if err != nil {
+		return fmt.Errorf("error closing log file - %w", err)
+	}
+	ext := filepath.Ext(file.Path)
+	backup := strings.TrimSuffix(file.Path, ext) + "-" + time.Now().UTC().Format(backupTimeFormat) + ext
+	err = os.Rename(file.Path, backup)
+	if err != nil && !os.IsNotExist(err) {
+		// keep logging to the old file rather than losing entries
+		openErr := file.open()
+		if openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("error rotating log file - %w", err)
+	}
+	err = file.open()
+	if err != nil {
+		return err
+	}
+	file.prune()
+	return nil
+}
+
+// prune removes the oldest backups beyond the retention limit
+func (file *RotatingFile) prune() {
+	if file.MaxBackups <= 0 {
+		return
+	}
+	backups, err := file.Backups()
+	if err != nil {
+		return
+	}
+	for len(backups) > file.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
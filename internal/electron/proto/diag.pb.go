@@ -0,0 +1,178 @@
+//
+//BrewTheory
+//Copyright (C) 2022  Joshua Farr
+//
+//This program is free software: you can redistribute it and/or modify
+//it under the terms of the GNU General Public License as published by
+//the Free Software Foundation, either version 3 of the License, or
+//(at your option) any later version.
+//
+//This program is distributed in the hope that it will be useful,
+//but WITHOUT ANY WARRANTY; without even the implied warranty of
+//MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//GNU General Public License for more details.
+//
+//You should have received a copy of the GNU General Public License
+//along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.21.5
+// source: diag.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Starts a job that writes a diagnostic bundle for support requests
+type CreateDiagnosticsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Header *RequestHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// leaves the logs out of the bundle
+	ExcludeLogs bool `protobuf:"varint,2,opt,name=excludeLogs,proto3" json:"excludeLogs,omitempty"`
+}
+
+func (x *CreateDiagnosticsRequest) Reset() {
+	*x = CreateDiagnosticsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_diag_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateDiagnosticsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDiagnosticsRequest) ProtoMessage() {}
+
+func (x *CreateDiagnosticsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_diag_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDiagnosticsRequest.ProtoReflect.Descriptor instead.
+func (*CreateDiagnosticsRequest) Descriptor() ([]byte, []int) {
+	return file_diag_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateDiagnosticsRequest) GetHeader() *RequestHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *CreateDiagnosticsRequest) GetExcludeLogs() bool {
+	if x != nil {
+		return x.ExcludeLogs
+	}
+	return false
+}
+
+var File_diag_proto protoreflect.FileDescriptor
+
+var file_diag_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x64, 0x69, 0x61, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0a, 0x62, 0x72,
+	0x65, 0x77, 0x74, 0x68, 0x65, 0x6f, 0x72, 0x79, 0x1a, 0x0c, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x6f, 0x0a, 0x18, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65,
+	0x44, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x31, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x62, 0x72, 0x65, 0x77, 0x74, 0x68, 0x65, 0x6f, 0x72, 0x79, 0x2e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06, 0x68,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x20, 0x0a, 0x0b, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x64, 0x65,
+	0x4c, 0x6f, 0x67, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x65, 0x78, 0x63, 0x6c,
+	0x75, 0x64, 0x65, 0x4c, 0x6f, 0x67, 0x73, 0x42, 0x19, 0x5a, 0x17, 0x69, 0x6e, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x2f, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x72, 0x6f, 0x6e, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_diag_proto_rawDescOnce sync.Once
+	file_diag_proto_rawDescData = file_diag_proto_rawDesc
+)
+
+func file_diag_proto_rawDescGZIP() []byte {
+	file_diag_proto_rawDescOnce.Do(func() {
+		file_diag_proto_rawDescData = protoimpl.X.CompressGZIP(file_diag_proto_rawDescData)
+	})
+	return file_diag_proto_rawDescData
+}
+
+var file_diag_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_diag_proto_goTypes = []interface{}{
+	(*CreateDiagnosticsRequest)(nil), // 0: brewtheory.CreateDiagnosticsRequest
+	(*RequestHeader)(nil),            // 1: brewtheory.RequestHeader
+}
+var file_diag_proto_depIdxs = []int32{
+	1, // 0: brewtheory.CreateDiagnosticsRequest.header:type_name -> brewtheory.RequestHeader
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_diag_proto_init() }
+func file_diag_proto_init() {
+	if File_diag_proto != nil {
+		return
+	}
+	file_common_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_diag_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateDiagnosticsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_diag_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_diag_proto_goTypes,
+		DependencyIndexes: file_diag_proto_depIdxs,
+		MessageInfos:      file_diag_proto_msgTypes,
+	}.Build()
+	File_diag_proto = out.File
+	file_diag_proto_rawDesc = nil
+	file_diag_proto_goTypes = nil
+	file_diag_proto_depIdxs = nil
+}
@@ -0,0 +1,62 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package proto
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// This file holds hand written validation for generated message types.
+// Typed RPC handlers call Validate before the handler sees the request.
+
+// MaxBatchCalls is the largest number of calls a single batch may carry
+const MaxBatchCalls = 64
+
+// Validate checks that the client sent a usable verification key
+func (x *KeyExchangeRequest) Validate() error {
+	if len(x.PublicKey) != ed25519.PublicKeySize {
+		return codes.NewApplication(codes.ScopeRPC, codes.ErrorInvalidRequest).
+			WithField("publicKey", "length", fmt.Sprintf("must be %d bytes", ed25519.PublicKeySize))
+	}
+	return nil
+}
+
+// Validate checks that a batch carries a reasonable number of named calls
+func (x *BatchRequest) Validate() error {
+	if len(x.Calls) == 0 || len(x.Calls) > MaxBatchCalls {
+		return codes.NewApplication(codes.ScopeRPC, codes.ErrorInvalidRequest).
+			WithField("calls", "count", fmt.Sprintf("must have between 1 & %d calls", MaxBatchCalls))
+	}
+	var err *codes.InternalError
+	for i, call := range x.Calls {
+		if call.Method == "" {
+			if err == nil {
+				err = codes.NewApplication(codes.ScopeRPC, codes.ErrorInvalidRequest)
+			}
+			err.WithField(fmt.Sprintf("calls[%d].method", i), "required", "a method name is required")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
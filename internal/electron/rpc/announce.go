@@ -0,0 +1,136 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// Events announced to the launching process on stdout
+const (
+	AnnounceReady = "ready"
+	AnnounceError = "error"
+)
+
+// ReadyAnnouncement is written to stdout as a single JSON line once the
+// server is listening.  It carries everything the launcher needs to connect
+// & pin the certificate, so several instances can run on ephemeral ports.
+type ReadyAnnouncement struct {
+	Event string `json:"event"`
+	// Address is the bound host:port, or the unix socket address
+	Address            string        `json:"address"`
+	TLS                bool          `json:"tls"`
+	Fingerprint        string        `json:"fingerprint,omitempty"`
+	PID                int           `json:"pid"`
+	ProtocolVersion    int32         `json:"protocolVersion"`
+	MinProtocolVersion int32         `json:"minProtocolVersion"`
+	AppVersion         string        `json:"appVersion,omitempty"`
+	Bootstrap          BootstrapInfo `json:"bootstrap"`
+}
+
+// BootstrapInfo tells the launcher how pairing is guarded
+type BootstrapInfo struct {
+	// Required is set when key exchanges must prove the bootstrap secret
+	Required    bool `json:"required"`
+	LockPairing bool `json:"lockPairing"`
+}
+
+// FailureAnnouncement is written to stdout as a single JSON line when the
+// service fails to start
+type FailureAnnouncement struct {
+	Event string       `json:"event"`
+	PID   int          `json:"pid"`
+	Error StartupError `json:"error"`
+}
+
+// StartupError is the error code that stopped the service from starting
+type StartupError struct {
+	Scope   codes.Scope `json:"scope"`
+	Code    codes.Code  `json:"code"`
+	Message string      `json:"message"`
+}
+
+// Failure renders the announcement for a startup error
+func Failure(err error) string {
+	internal := codes.ToInternalError(err)
+	announcement := &FailureAnnouncement{
+		Event: AnnounceError,
+		PID:   os.Getpid(),
+		Error: StartupError{
+			Scope:   internal.Scope,
+			Code:    internal.Code,
+			Message: internal.Message,
+		},
+	}
+	return announce(announcement)
+}
+
+// announceReady renders the announcement for a listening server
+func (rpc *Server) announceReady(listener net.Listener, address string) string {
+	if !IsUnixAddress(address) {
+		address = listener.Addr().String()
+	}
+	announcement := &ReadyAnnouncement{
+		Event:              AnnounceReady,
+		Address:            address,
+		TLS:                !rpc.DisableTLS,
+		PID:                os.Getpid(),
+		ProtocolVersion:    ProtocolVersion,
+		MinProtocolVersion: MinProtocolVersion,
+		AppVersion:         rpc.AppVersion,
+		Bootstrap: BootstrapInfo{
+			Required:    rpc.Pairing.Required(),
+			LockPairing: rpc.Pairing.Lock,
+		},
+	}
+	if !rpc.DisableTLS {
+		announcement.Fingerprint = rpc.Identity.Fingerprint()
+	}
+	return announce(announcement)
+}
+
+// fail announces a startup error & asks the service to shut down
+func (rpc *Server) fail(err error) {
+	rpc.sendStatus(Failure(err))
+	rpc.RequestShutdown(false)
+}
+
+// sendStatus passes a status line to the owning service.  The service stops
+// reading once it begins shutting down, so the send gives up when the server
+// is stopped rather than blocking forever.
+func (rpc *Server) sendStatus(status string) {
+	select {
+	case rpc.Status <- status:
+	case <-rpc.done:
+		rpc.Logger.Debug("Server stopped before status was sent")
+	}
+}
+
+func announce(announcement interface{}) string {
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		// the announcements only hold plain values so this can't happen
+		return `{"event":"error"}`
+	}
+	return string(data)
+}
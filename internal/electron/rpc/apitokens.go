@@ -0,0 +1,231 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// APITokenPrefix starts every API token so they are easy to recognise
+const APITokenPrefix = "bt_"
+
+// APIToken is a long lived credential for the JSON gateway.
+// Only a digest of the token is stored, the token itself is shown once when
+// it is created.
+type APIToken struct {
+	Name    string    `json:"name"`
+	Digest  string    `json:"digest"`
+	Created time.Time `json:"created"`
+}
+
+// APITokens is the set of API tokens persisted in the config directory.
+// The file is reloaded whenever it changes so tokens created or revoked from
+// the command line take effect without restarting the service.
+type APITokens struct {
+	Logger *logrus.Logger
+
+	path     string
+	mutex    sync.Mutex
+	tokens   []*APIToken
+	modified time.Time
+	size     int64
+}
+
+// NewAPITokens opens the API tokens stored in the config directory
+func NewAPITokens(logger *logrus.Logger) (*APITokens, bool) {
+	dir, ok := ConfigDir(logger)
+	if !ok {
+		return nil, false
+	}
+	store := &APITokens{
+		Logger: logger,
+		path:   filepath.Join(dir, "api-tokens.json"),
+	}
+	return store, true
+}
+
+// Create adds a named token & returns the token itself
+func (store *APITokens) Create(name string) (string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("api token name is required")
+	}
+	err := store.load()
+	if err != nil {
+		return "", err
+	}
+	for _, token := range store.tokens {
+		if token.Name == name {
+			return "", fmt.Errorf("api token [%s] already exists", name)
+		}
+	}
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("error generating api token - %w", err)
+	}
+	value := APITokenPrefix + hex.EncodeToString(secret)
+	store.tokens = append(store.tokens, &APIToken{
+		Name:    name,
+		Digest:  tokenDigest(value),
+		Created: time.Now().UTC(),
+	})
+	err = store.save()
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// Revoke removes a named token.  False is returned when there was no such
+// token.
+func (store *APITokens) Revoke(name string) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.load()
+	if err != nil {
+		return false, err
+	}
+	for i, token := range store.tokens {
+		if token.Name == name {
+			store.tokens = append(store.tokens[:i], store.tokens[i+1:]...)
+			return true, store.save()
+		}
+	}
+	return false, nil
+}
+
+// List returns every token sorted by name
+func (store *APITokens) List() ([]APIToken, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]APIToken, 0, len(store.tokens))
+	for _, token := range store.tokens {
+		list = append(list, *token)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Authenticate finds the token a client presented
+func (store *APITokens) Authenticate(value string) (*APIToken, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.load()
+	if err != nil {
+		store.Logger.Warn("Error loading api tokens - ", err)
+		return nil, false
+	}
+	if !strings.HasPrefix(value, APITokenPrefix) {
+		return nil, false
+	}
+	digest := []byte(tokenDigest(value))
+	for _, token := range store.tokens {
+		if subtle.ConstantTimeCompare(digest, []byte(token.Digest)) == 1 {
+			return token, true
+		}
+	}
+	return nil, false
+}
+
+// load reads the tokens when the file has changed since it was last read.
+// The store must be locked.
+func (store *APITokens) load() error {
+	info, err := os.Stat(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		store.tokens = nil
+		store.modified = time.Time{}
+		store.size = 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading api tokens - %w", err)
+	}
+	if info.ModTime().Equal(store.modified) && info.Size() == store.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		return fmt.Errorf("error reading api tokens - %w", err)
+	}
+	var tokens []*APIToken
+	err = json.Unmarshal(data, &tokens)
+	if err != nil {
+		return fmt.Errorf("error decoding api tokens - %w", err)
+	}
+	store.tokens = tokens
+	store.modified = info.ModTime()
+	store.size = info.Size()
+	return nil
+}
+
+// save persists the tokens.  The file is replaced atomically so a crash can't
+// leave it half written.
+// The store must be locked.
+func (store *APITokens) save() error {
+	data, err := json.MarshalIndent(store.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding api tokens - %w", err)
+	}
+	temp := store.path + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err == nil {
+		err = os.Rename(temp, store.path)
+	}
+	if err != nil {
+		return fmt.Errorf("error saving api tokens - %w", err)
+	}
+	// the next load picks up the new modification time
+	store.modified = time.Time{}
+	return nil
+}
+
+// tokenDigest is the hex encoded SHA-256 digest a token is stored as
+func tokenDigest(value string) string {
+	digest := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(digest[:])
+}
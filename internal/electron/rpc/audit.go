@@ -0,0 +1,280 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// AuditChange records how a single entity was changed by a call.
+// Before & After are digests of the entity's state, empty when the entity
+// didn't exist.
+type AuditChange struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// AuditEntry records a call to a mutating method
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Session identifies the session the call was made by without revealing
+	// its token, APIToken names the API token of a JSON gateway call
+	Session  string        `json:"session,omitempty"`
+	APIToken string        `json:"apiToken,omitempty"`
+	Method   string        `json:"method"`
+	Sequence int32         `json:"sequence,omitempty"`
+	Changes  []AuditChange `json:"changes,omitempty"`
+	Code     codes.Code    `json:"code"`
+	Scope    codes.Scope   `json:"scope"`
+	// RolledBack is set when the call was part of an atomic batch that failed
+	// & its changes were undone
+	RolledBack bool `json:"rolledBack,omitempty"`
+
+	// whether the call registered an undo for its changes
+	undoable bool
+}
+
+// AuditFilter selects entries from the journal.  Zero fields match
+// everything.
+type AuditFilter struct {
+	Method     string
+	Session    string
+	EntityType string
+	EntityID   string
+	Since      time.Time
+	Until      time.Time
+	// Limit keeps only the newest entries
+	Limit int
+}
+
+// AuditJournal is an append-only log of calls to mutating methods, one JSON
+// entry per line, kept in the config directory
+type AuditJournal struct {
+	Logger *logrus.Logger
+
+	path  string
+	mutex sync.Mutex
+}
+
+// NewAuditJournal opens the audit journal in the config directory
+func NewAuditJournal(logger *logrus.Logger) (*AuditJournal, bool) {
+	dir, ok := ConfigDir(logger)
+	if !ok {
+		return nil, false
+	}
+	journal := &AuditJournal{
+		Logger: logger,
+		path:   filepath.Join(dir, "audit.jsonl"),
+	}
+	return journal, true
+}
+
+// Append adds entries to the end of the journal
+func (journal *AuditJournal) Append(entries ...AuditEntry) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	file, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening audit journal - %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		err = encoder.Encode(entry)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing audit journal - %w", err)
+	}
+	return nil
+}
+
+// Query returns the entries that match a filter, oldest first
+func (journal *AuditJournal) Query(filter AuditFilter) ([]AuditEntry, error) {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	file, err := os.Open(journal.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit journal - %w", err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var entry AuditEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			// a torn final line from a crash shouldn't hide the rest
+			journal.Logger.Warn("Skipping unreadable audit entry - ", err)
+			continue
+		}
+		if !filter.matches(&entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) > filter.Limit {
+			entries = entries[1:]
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error reading audit journal - %w", err)
+	}
+	return entries, nil
+}
+
+func (filter *AuditFilter) matches(entry *AuditEntry) bool {
+	if filter.Method != "" && entry.Method != filter.Method {
+		return false
+	}
+	if filter.Session != "" && entry.Session != filter.Session && entry.APIToken != filter.Session {
+		return false
+	}
+	if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !entry.Time.Before(filter.Until) {
+		return false
+	}
+	if filter.EntityType == "" && filter.EntityID == "" {
+		return true
+	}
+	for _, change := range entry.Changes {
+		if (filter.EntityType == "" || change.Type == filter.EntityType) && (filter.EntityID == "" || change.ID == filter.EntityID) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditDigest is the digest of an entity's state recorded in the journal.
+// Nil messages have an empty digest.
+func AuditDigest(message proto.Message) string {
+	if message == nil || !message.ProtoReflect().IsValid() {
+		return ""
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return ""
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// Changed records that the call changed an entity.  Before & after are the
+// entity's state, nil when it didn't exist.  The RPC layer writes the changes
+// to the audit journal once the call has finished.
+func (context *RequestContext) Changed(kind string, id string, before proto.Message, after proto.Message) {
+	context.changes = append(context.changes, AuditChange{
+		Type:   kind,
+		ID:     id,
+		Before: AuditDigest(before),
+		After:  AuditDigest(after),
+	})
+}
+
+// Audit is middleware that records every call to a mutating method, along
+// with the entities its handler reported changing.  Entries are written once
+// the whole request has finished so calls undone by a failed atomic batch
+// can be marked as rolled back.
+func Audit() Middleware {
+	return func(next Handler) Handler {
+		return func(server *Server, message []byte, context *RequestContext) (proto.Message, error) {
+			method, ok := server.Methods[context.Header.Method]
+			if server.Audit == nil || !ok || !method.Mutating {
+				return next(server, message, context)
+			}
+
+			response, err := next(server, message, context)
+			entry := AuditEntry{
+				Time:     time.Now().UTC(),
+				Method:   context.Header.Method,
+				Sequence: context.Header.Sequence,
+				Changes:  context.changes,
+			}
+			entry.undoable = context.undoable
+			if context.Token != nil {
+				entry.Session = context.Token.ID()
+			}
+			if context.APIToken != nil {
+				entry.APIToken = context.APIToken.Name
+			}
+			result := err
+			if result == nil {
+				if header := responseHeader(response); header != nil && codes.Code(header.Code) != codes.ErrorOK {
+					result = codes.New(codes.Scope(header.Scope), codes.Code(header.Code))
+				}
+			}
+			if result != nil {
+				internal := codes.ToInternalError(result)
+				entry.Code = internal.Code
+				entry.Scope = internal.Scope
+			}
+			context.root().audits = append(context.root().audits, entry)
+			return response, err
+		}
+	}
+}
+
+// writeAudit writes the audit entries a request collected
+func (rpc *Server) writeAudit(context *RequestContext) {
+	if rpc.Audit == nil || len(context.audits) == 0 {
+		return
+	}
+	err := rpc.Audit.Append(context.audits...)
+	if err != nil {
+		rpc.Logger.Error("Error writing audit entries for method [", context.Header.Method, "] - ", err)
+	}
+	context.audits = nil
+}
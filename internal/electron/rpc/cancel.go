@@ -0,0 +1,73 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// callContext derives the context a call runs under from its HTTP request &
+// timeout.  Calls from a session are tracked by their sequence so the client
+// can cancel them.  The returned function must be called once the call has
+// finished.
+func (rpc *Server) callContext(req *http.Request, context *RequestContext) (context.Context, func()) {
+	ctx, cancel := contextWithTimeout(req.Context(), context.Header.Timeout)
+	if context.Token == nil {
+		return ctx, cancel
+	}
+	token := context.Token
+	sequence := context.Header.Sequence
+	token.Track(sequence, cancel)
+	return ctx, func() {
+		token.Untrack(sequence)
+		cancel()
+	}
+}
+
+func contextWithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// Err returns the error a handler should stop with once its call has been
+// cancelled or has run out of time, or nil while it may keep going
+func (context *RequestContext) Err() error {
+	if context.Context == nil {
+		return nil
+	}
+	return callError(context.Context.Err())
+}
+
+// callError converts the error of a finished context into an error code
+func callError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return codes.New(codes.ScopeRPC, codes.ErrorDeadlineExceeded)
+	}
+	return codes.New(codes.ScopeRPC, codes.ErrorCancelled)
+}
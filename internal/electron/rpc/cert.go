@@ -23,9 +23,11 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"math/big"
@@ -35,18 +37,123 @@ import (
 	"time"
 
 	"github.com/shibukawa/configdir"
+	"github.com/sirupsen/logrus"
 )
 
-func (rpc *Server) createCertificate() bool {
+// Certificate lifetimes
+const (
+	// CertificateValidity is how long a newly generated certificate is valid
+	CertificateValidity = 365 * 24 * time.Hour
+	// CertificateRenewBefore is how long before expiry a certificate is rotated
+	CertificateRenewBefore = 30 * 24 * time.Hour
+)
+
+// Identity is the persisted TLS key pair the server presents to clients.
+// The certificate & key are kept in the config directory so clients can pin
+// the same certificate across restarts until it is rotated.
+type Identity struct {
+	Logger      *logrus.Logger
+	CertPath    string
+	KeyPath     string
+	Certificate tls.Certificate
+	Leaf        *x509.Certificate
+}
+
+// ConfigDir returns the backend's global config directory, creating it if
+// it doesn't exist yet
+func ConfigDir(logger *logrus.Logger) (string, bool) {
+	configDirs := configdir.New("", "BrewTheory")
+	folders := configDirs.QueryFolders(configdir.Global)
+	if _, err := os.Stat(folders[0].Path); errors.Is(err, os.ErrNotExist) {
+		logger.Debug("Creating missing config directory - ", folders[0].Path)
+		err := os.MkdirAll(folders[0].Path, os.ModePerm)
+		if err != nil {
+			logger.Error("Error creating config directory - ", err)
+			return "", false
+		}
+	}
+	return folders[0].Path, true
+}
+
+// NewIdentity creates an identity stored in the config directory
+func NewIdentity(logger *logrus.Logger) (*Identity, bool) {
+	dir, ok := ConfigDir(logger)
+	if !ok {
+		return nil, false
+	}
+	identity := &Identity{
+		Logger:   logger,
+		CertPath: filepath.Join(dir, "certificate"),
+		KeyPath:  filepath.Join(dir, "certificate.key"),
+	}
+	return identity, true
+}
+
+// Load reads the persisted key pair.
+// A new key pair is generated if none exists, if it can't be read, or if the
+// certificate is close to expiring.
+func (identity *Identity) Load() bool {
+	cert, err := tls.LoadX509KeyPair(identity.CertPath, identity.KeyPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			identity.Logger.Warn("Error loading certificate - ", err)
+		}
+		return identity.Rotate()
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		identity.Logger.Warn("Error parsing certificate - ", err)
+		return identity.Rotate()
+	}
+	if time.Until(leaf.NotAfter) < CertificateRenewBefore {
+		identity.Logger.Info("Certificate expires at [", leaf.NotAfter, "], rotating")
+		return identity.Rotate()
+	}
+
+	identity.Certificate = cert
+	identity.Leaf = leaf
+	identity.Logger.Debug("Loaded certificate from: ", identity.CertPath)
+	return true
+}
+
+// Fingerprint returns the hex encoded SHA-256 digest of the certificate
+func (identity *Identity) Fingerprint() string {
+	if identity.Leaf == nil {
+		return ""
+	}
+	return CertificateFingerprint(identity.Leaf)
+}
+
+// CertificateFingerprint returns the hex encoded SHA-256 digest of a
+// certificate
+func CertificateFingerprint(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(digest[:])
+}
+
+// ReadCertificate reads the persisted certificate without loading the key or
+// generating a new pair when it is missing
+func (identity *Identity) ReadCertificate() (*x509.Certificate, error) {
+	data, err := os.ReadFile(identity.CertPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// Rotate generates & persists a new key pair
+func (identity *Identity) Rotate() bool {
 	now := time.Now()
-	notBefore := now.Add(-time.Hour * 24)
-	// end of ASN.1 time
-	endOfTime := time.Date(2049, 12, 31, 23, 59, 59, 0, time.UTC)
-	notAfter := endOfTime // notBefore.Add(365 * 24 * time.Hour)
+	notBefore := now.Add(-time.Hour)
+	notAfter := now.Add(CertificateValidity)
 
 	host, err := os.Hostname()
 	if err != nil {
-		rpc.Logger.Warn("Error getting hostname - ", err)
+		identity.Logger.Warn("Error getting hostname - ", err)
 		return false
 	}
 
@@ -67,7 +174,7 @@ func (rpc *Server) createCertificate() bool {
 
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
-		rpc.Logger.Warn("error getting interface addresses - ", err)
+		identity.Logger.Warn("error getting interface addresses - ", err)
 		return false
 	}
 	for _, a := range addrs {
@@ -80,26 +187,28 @@ func (rpc *Server) createCertificate() bool {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
-		rpc.Logger.Warn("Error creating serial number - ", err)
+		identity.Logger.Warn("Error creating serial number - ", err)
 		return false
 	}
 
 	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
-		rpc.Logger.Warn("Error generating private key - ", err)
+		identity.Logger.Warn("Error generating private key - ", err)
 		return false
 	}
 
+	// a self-signed leaf certificate - it can't be used to sign anything else
 	template := x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
-			Organization: []string{"NoteKeeper.io"},
+			Organization: []string{"BrewTheory"},
 			CommonName:   host,
 		},
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
-		IsCA:                  true,
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  false,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
 		DNSNames:              dnsNames,
 		IPAddresses:           ipAddresses,
@@ -107,53 +216,55 @@ func (rpc *Server) createCertificate() bool {
 
 	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privKey.PublicKey, privKey)
 	if err != nil {
-		rpc.Logger.Warn("Error creating certificate - ", err)
+		identity.Logger.Warn("Error creating certificate - ", err)
 		return false
 	}
 
 	certBuf := &bytes.Buffer{}
 	err = pem.Encode(certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
 	if err != nil {
-		rpc.Logger.Warn("Error encoding certificate - ", err)
+		identity.Logger.Warn("Error encoding certificate - ", err)
 		return false
 	}
 
 	keyBytes, err := x509.MarshalECPrivateKey(privKey)
 	if err != nil {
-		rpc.Logger.Warn("Error marshaling key bytes - ", err)
+		identity.Logger.Warn("Error marshaling key bytes - ", err)
 		return false
 	}
 
 	keyBuf := &bytes.Buffer{}
 	err = pem.Encode(keyBuf, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
 	if err != nil {
-		rpc.Logger.Warn("Error encoding key - ", err)
+		identity.Logger.Warn("Error encoding key - ", err)
 		return false
 	}
 
-	rpc.Certificate, err = tls.X509KeyPair(certBuf.Bytes(), keyBuf.Bytes())
+	cert, err := tls.X509KeyPair(certBuf.Bytes(), keyBuf.Bytes())
+	if err != nil {
+		identity.Logger.Warn("Error converting certificate - ", err)
+		return false
+	}
+	leaf, err := x509.ParseCertificate(derBytes)
 	if err != nil {
-		rpc.Logger.Warn("Error converting certificate - ", err)
+		identity.Logger.Warn("Error parsing certificate - ", err)
 		return false
 	}
 
-	configDirs := configdir.New("", "BrewTheory")
-	folders := configDirs.QueryFolders(configdir.Global)
-	if _, err := os.Stat(folders[0].Path); errors.Is(err, os.ErrNotExist) {
-		rpc.Logger.Debug("Creating missing config directory - ", folders[0].Path)
-		err := os.MkdirAll(folders[0].Path, os.ModePerm)
-		if err != nil {
-			rpc.Logger.Error("Error creating config directory - ", err)
-			return false
-		}
+	// write the key first so a certificate never exists on disk without its key
+	err = os.WriteFile(identity.KeyPath, keyBuf.Bytes(), 0600)
+	if err != nil {
+		identity.Logger.Warn("Error writing certificate key - ", err)
+		return false
 	}
-	certPath := filepath.Join(folders[0].Path, "certificate")
-	rpc.Logger.Debug("Wrote certificate to: ", certPath)
-	err = os.WriteFile(certPath, certBuf.Bytes(), 0600)
+	err = os.WriteFile(identity.CertPath, certBuf.Bytes(), 0600)
 	if err != nil {
-		rpc.Logger.Warn("Error writing certificate - ", err)
+		identity.Logger.Warn("Error writing certificate - ", err)
 		return false
 	}
+	identity.Logger.Debug("Wrote certificate to: ", identity.CertPath)
 
+	identity.Certificate = cert
+	identity.Leaf = leaf
 	return true
 }
@@ -0,0 +1,112 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Envelope kinds keep request, response & event signatures from being
+// interchangeable
+const (
+	EnvelopeRequest  = "brewtheory-request-v1"
+	EnvelopeResponse = "brewtheory-response-v1"
+	EnvelopeEvent    = "brewtheory-event-v1"
+)
+
+// DefaultMaxClockSkew is how far a message timestamp may drift from the
+// server clock before the message is rejected
+const DefaultMaxClockSkew = 30 * time.Second
+
+// Envelope is the canonical form of a message that is signed.
+// Binding the method, token, sequence & timestamp into the signature means a
+// message can't be replayed against a different method or session, or have
+// its sequence rewritten, without the signature failing.
+type Envelope struct {
+	Kind      string
+	Method    string
+	Token     string
+	Sequence  int32
+	Timestamp int64 // unix milliseconds
+	Body      []byte
+}
+
+// Bytes returns the canonical encoding of the envelope.
+// Each field is on its own line & the body is represented by the hex encoded
+// SHA-256 digest of the decoded protobuf bytes.
+func (envelope *Envelope) Bytes() []byte {
+	digest := sha256.Sum256(envelope.Body)
+	canonical := fmt.Sprintf("%s\n%s\n%s\n%d\n%d\n%s",
+		envelope.Kind,
+		envelope.Method,
+		envelope.Token,
+		envelope.Sequence,
+		envelope.Timestamp,
+		hex.EncodeToString(digest[:]),
+	)
+	return []byte(canonical)
+}
+
+// replayCache remembers recently seen request signatures.
+// Sequence numbers already stop replays within a session, but key exchange
+// requests aren't part of a session so they rely on this instead.
+// Signatures are queued in the order they were seen, so expiring them only
+// looks at the ones that have actually expired.
+type replayCache struct {
+	mutex sync.Mutex
+	seen  map[string]struct{}
+	order []replayEntry
+}
+
+// replayEntry is a signature along with when it was seen
+type replayEntry struct {
+	key  string
+	seen time.Time
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{
+		seen: make(map[string]struct{}),
+	}
+}
+
+// Add records a signature & reports whether it was seen before.
+// Signatures only need to be kept for the window in which their timestamp
+// would still be accepted.
+func (cache *replayCache) Add(signature []byte, now time.Time, window time.Duration) bool {
+	key := string(signature)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	expired := 0
+	for expired < len(cache.order) && now.Sub(cache.order[expired].seen) > window {
+		delete(cache.seen, cache.order[expired].key)
+		expired++
+	}
+	cache.order = cache.order[expired:]
+	if _, ok := cache.seen[key]; ok {
+		return true
+	}
+	cache.seen[key] = struct{}{}
+	cache.order = append(cache.order, replayEntry{key: key, seen: now})
+	return false
+}
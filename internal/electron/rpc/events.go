@@ -0,0 +1,250 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+)
+
+// TopicAll subscribes to every published topic
+const TopicAll = "*"
+
+// eventQueueSize is the number of undelivered events a subscriber may have
+// queued before it is considered too slow & disconnected
+const eventQueueSize = 64
+
+// eventKeepAlive is how often an idle stream sends a comment line so
+// intermediaries don't time out the connection
+const eventKeepAlive = 15 * time.Second
+
+// Subscription is a single client's event stream
+type Subscription struct {
+	Token  *ClientToken
+	Topics map[string]bool
+
+	queue  chan *messages.Event
+	done   chan struct{}
+	closer sync.Once
+}
+
+// EventBus fans published events out to subscribed clients
+type EventBus struct {
+	Logger        *logrus.Logger
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]struct{}
+}
+
+// NewEventBus creates a new EventBus
+func NewEventBus(logger *logrus.Logger) *EventBus {
+	bus := &EventBus{
+		Logger:        logger,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+	return bus
+}
+
+// Publish sends a message to every subscriber of the topic.
+// Publishing never blocks, so it is safe to call from handlers and
+// background services.  A subscriber that has fallen too far behind is
+// disconnected and must resubscribe.
+func (bus *EventBus) Publish(topic string, message proto.Message) bool {
+	if topic == "" || strings.ContainsAny(topic, "\r\n") {
+		bus.Logger.Warn("Invalid event topic [", topic, "]")
+		return false
+	}
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		bus.Logger.Warn("Error marshaling event payload - ", err)
+		return false
+	}
+	timestamp := time.Now().UnixMilli()
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for sub := range bus.subscriptions {
+		if !sub.Topics[topic] && !sub.Topics[TopicAll] {
+			continue
+		}
+		event := &messages.Event{
+			Topic:     topic,
+			Timestamp: timestamp,
+			Payload:   payload,
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			bus.Logger.Warn("Event queue overflow, closing subscription for topic [", topic, "]")
+			bus.remove(sub)
+		}
+	}
+	return true
+}
+
+// Subscribe registers a new subscription for a client
+func (bus *EventBus) Subscribe(token *ClientToken, topics []string) *Subscription {
+	sub := &Subscription{
+		Token:  token,
+		Topics: make(map[string]bool, len(topics)),
+		queue:  make(chan *messages.Event, eventQueueSize),
+		done:   make(chan struct{}),
+	}
+	for _, topic := range topics {
+		sub.Topics[topic] = true
+	}
+
+	bus.mutex.Lock()
+	bus.subscriptions[sub] = struct{}{}
+	bus.mutex.Unlock()
+	return sub
+}
+
+// Unsubscribe removes a subscription & ends its stream
+func (bus *EventBus) Unsubscribe(sub *Subscription) {
+	bus.mutex.Lock()
+	bus.remove(sub)
+	bus.mutex.Unlock()
+}
+
+// CloseToken ends every stream that belongs to a client token
+func (bus *EventBus) CloseToken(token *ClientToken) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for sub := range bus.subscriptions {
+		if sub.Token == token {
+			bus.remove(sub)
+		}
+	}
+}
+
+// Close ends every stream
+func (bus *EventBus) Close() {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for sub := range bus.subscriptions {
+		bus.remove(sub)
+	}
+}
+
+// remove must be called with the bus mutex held
+func (bus *EventBus) remove(sub *Subscription) {
+	delete(bus.subscriptions, sub)
+	sub.closer.Do(func() {
+		close(sub.done)
+	})
+}
+
+// ServeEvents streams events to a subscribed client as server-sent events.
+// Each event is a hex encoded EventEnvelope carrying the encoded Event & its
+// signature.  Events are numbered from 1 for each stream so the client can
+// detect gaps & reordering.
+func (rpc *Server) ServeEvents(resp http.ResponseWriter, req *http.Request, message []byte, context *RequestContext) {
+	flusher, ok := resp.(http.Flusher)
+	if !ok {
+		rpc.Logger.Warn("Response writer does not support streaming")
+		rpc.WriteError(resp, context, codes.New(codes.ScopeRPC, codes.ErrorUnknown))
+		return
+	}
+
+	request := messages.SubscribeRequest{}
+	err := proto.Unmarshal(message, &request)
+	if err != nil {
+		rpc.Logger.Warn("Error unmarshaling subscribe request - ", err)
+		rpc.WriteError(resp, context, codes.New(codes.ScopeRPC, codes.ErrorDecode))
+		return
+	}
+
+	sub := rpc.Events.Subscribe(context.Token, request.Topics)
+	defer rpc.Events.Unsubscribe(sub)
+	rpc.Logger.Debug("Client subscribed to topics ", request.Topics)
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Request-Method", context.Header.Method)
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	var sequence int32
+	for {
+		select {
+		case event := <-sub.queue:
+			sequence++
+			event.Sequence = sequence
+			frame, ok := rpc.encodeEvent(event, context)
+			if !ok {
+				return
+			}
+			_, err = resp.Write(frame)
+		case <-keepAlive.C:
+			_, err = resp.Write([]byte(": keepalive\n\n"))
+		case <-sub.done:
+			return
+		case <-req.Context().Done():
+			return
+		}
+		if err != nil {
+			rpc.Logger.Debug("Error writing event stream - ", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// encodeEvent signs an event & renders it as a server-sent event frame
+func (rpc *Server) encodeEvent(event *messages.Event, context *RequestContext) ([]byte, bool) {
+	eventData, err := proto.Marshal(event)
+	if err != nil {
+		rpc.Logger.Warn("Error marshaling event - ", err)
+		return nil, false
+	}
+	// events are signed like responses, so they are bound to the session &
+	// can't be passed off as a response or moved to another stream position
+	signed := &Envelope{
+		Kind:      EnvelopeEvent,
+		Method:    context.Header.Method,
+		Token:     context.Token.Token,
+		Sequence:  event.Sequence,
+		Timestamp: event.Timestamp,
+		Body:      eventData,
+	}
+	envelope := &messages.EventEnvelope{
+		Event:     eventData,
+		Signature: context.Token.Sign(signed.Bytes()),
+	}
+	envelopeData, err := proto.Marshal(envelope)
+	if err != nil {
+		rpc.Logger.Warn("Error marshaling event envelope - ", err)
+		return nil, false
+	}
+	frame := fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Topic, hex.EncodeToString(envelopeData))
+	return []byte(frame), true
+}
@@ -0,0 +1,199 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// GatewayPrefix is the path JSON gateway calls are made under, followed by
+// the method name
+const GatewayPrefix = "/json/"
+
+// ContentTypeJSON is the content type of JSON gateway requests & responses
+const ContentTypeJSON = "application/json"
+
+// serveJSON handles a call made through the JSON gateway.
+// The request body is the protojson form of the method's request message &
+// the response is the protojson form of its response message.  Calls are
+// authenticated with an API token instead of a signed session, & otherwise
+// go through the same handlers, middleware & error codes as "/rpc".
+func (rpc *Server) serveJSON(resp http.ResponseWriter, req *http.Request, context *RequestContext) {
+	if req.Method != "POST" {
+		rpc.Logger.Warn("Unexpected request method - ", req.Method)
+		resp.Header().Set("Allow", "POST")
+		rpc.jsonReject(resp, codes.New(codes.ScopeRPC, codes.ErrorUnsupportedVerb))
+		return
+	}
+
+	method := strings.TrimPrefix(req.URL.Path, GatewayPrefix)
+	context.Header = &RequestHeader{Method: method}
+
+	host := remoteHost(req)
+	value, ok := bearerToken(req)
+	if ok {
+		context.APIToken, ok = rpc.APITokens.Authenticate(value)
+	}
+	if !ok {
+		if allowed, wait := rpc.limiter.allow("preauth:"+host, rpc.Limits.PreAuth, time.Now()); !allowed {
+			rpc.Logger.Warn("Pre-auth rate limit exceeded for [", host, "]")
+			rpc.jsonRateLimited(resp, RejectRateLimitedPreAuth, wait)
+			return
+		}
+		rpc.Logger.Warn("Missing or invalid api token")
+		rpc.jsonReject(resp, codes.New(codes.ScopeRPC, codes.ErrorUnauthenticated))
+		return
+	}
+	if allowed, wait := rpc.limiter.allow("api:"+context.APIToken.Name, rpc.Limits.Token, time.Now()); !allowed {
+		rpc.Logger.Warn("Rate limit exceeded for api token [", context.APIToken.Name, "]")
+		rpc.jsonRateLimited(resp, RejectRateLimitedToken, wait)
+		return
+	}
+
+	description, ok := rpc.Methods[method]
+	handler := rpc.FindHandler(method)
+	if !ok || handler == nil || description.Request == nil || description.SessionOnly {
+		rpc.Logger.Warn("Method [", method, "] is not available through the json gateway")
+		rpc.jsonReject(resp, codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod))
+		return
+	}
+	requestType, err := protoregistry.GlobalTypes.FindMessageByName(description.Request.FullName())
+	if err != nil {
+		rpc.Logger.Warn("Unknown request type for method [", method, "] - ", err)
+		rpc.jsonReject(resp, codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod))
+		return
+	}
+
+	context.Header.Timeout, err = rpc.requestTimeout(req)
+	if err != nil {
+		rpc.jsonReject(resp, err)
+		return
+	}
+
+	message, err := rpc.readJSON(resp, req, requestType.New().Interface())
+	if err != nil {
+		rpc.jsonReject(resp, err)
+		return
+	}
+
+	var cancel func()
+	context.Context, cancel = rpc.callContext(req, context)
+	defer cancel()
+
+	rpc.Logger.Debug("JSON gateway request for RPC method [", method, "] with api token [", context.APIToken.Name, "]")
+	response, err := rpc.chain(handler)(rpc, message, context)
+	rpc.writeAudit(context)
+	if err != nil && context.Err() != nil {
+		err = context.Err()
+	}
+	if err != nil {
+		rpc.Logger.Error("Handler for method [", method, "] failed - ", err)
+		rpc.writeJSON(resp, HTTPStatus(codes.ToInternalError(err).Code), errorResponse(err))
+		return
+	}
+	rpc.writeJSON(resp, http.StatusOK, response)
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(req *http.Request) (string, bool) {
+	scheme, value, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	return value, value != ""
+}
+
+// readJSON decodes a JSON request body into protobuf bytes.
+// An empty body is treated as an empty message.
+func (rpc *Server) readJSON(resp http.ResponseWriter, req *http.Request, message proto.Message) ([]byte, error) {
+	var reader io.Reader = req.Body
+	if rpc.Limits.MaxBodySize > 0 {
+		if req.ContentLength > rpc.Limits.MaxBodySize {
+			rpc.Logger.Warn("Request body of [", req.ContentLength, "] bytes is too large")
+			return nil, codes.New(codes.ScopeRPC, codes.ErrorBodyTooLarge)
+		}
+		reader = http.MaxBytesReader(resp, req.Body, rpc.Limits.MaxBodySize)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			rpc.Logger.Warn("Request body is too large")
+			return nil, codes.New(codes.ScopeRPC, codes.ErrorBodyTooLarge)
+		}
+		rpc.Logger.Warn("Error reading request body - ", err)
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorBadRequest)
+	}
+	if len(strings.TrimSpace(string(body))) != 0 {
+		err = protojson.Unmarshal(body, message)
+		if err != nil {
+			rpc.Logger.Warn("Error decoding json request - ", err)
+			return nil, codes.New(codes.ScopeRPC, codes.ErrorDecode)
+		}
+	}
+	encoded, err := proto.Marshal(message)
+	if err != nil {
+		rpc.Logger.Warn("Error encoding request - ", err)
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorMarshal)
+	}
+	return encoded, nil
+}
+
+// jsonReject counts & reports a gateway request refused before reaching its
+// handler
+func (rpc *Server) jsonReject(resp http.ResponseWriter, err error) {
+	rpc.Rejections.Add(rejectReason(codes.ToInternalError(err).Code))
+	rpc.writeJSON(resp, HTTPStatus(codes.ToInternalError(err).Code), errorResponse(err))
+}
+
+// jsonRateLimited refuses a gateway request, telling the client when it may
+// try again
+func (rpc *Server) jsonRateLimited(resp http.ResponseWriter, reason string, wait time.Duration) {
+	resp.Header().Set("Retry-After", retryAfter(wait))
+	rpc.Rejections.Add(reason)
+	rpc.writeJSON(resp, http.StatusTooManyRequests, errorResponse(codes.New(codes.ScopeRPC, codes.ErrorRateLimited).WithRetry(wait)))
+}
+
+// writeJSON sends a response message as JSON.  Every field is included, even
+// when it has its default value, so scripts don't have to know the defaults.
+func (rpc *Server) writeJSON(resp http.ResponseWriter, status int, message proto.Message) {
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(message)
+	if err != nil {
+		rpc.Logger.Warn("Error encoding json response - ", err)
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	resp.Header().Set("Content-Type", ContentTypeJSON)
+	resp.WriteHeader(status)
+	_, err = resp.Write(append(data, '\n'))
+	if err != nil {
+		rpc.Logger.Warn("Error writing response - ", err)
+	}
+}
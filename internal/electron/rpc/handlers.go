@@ -18,7 +18,43 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 package rpc
 
-// RegisterHandlers registers all of the RPC handlers
-func (rpc *Server) RegisterHandlers(handlers map[string]Handler) {
-	rpc.Handlers = handlers
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// RegisterHandler registers a single RPC handler.
+// The method is listed in the catalog without message schemas.
+func (rpc *Server) RegisterHandler(method string, handler Handler, options ...MethodOption) {
+	rpc.registerMethod(newMethod(method, nil, nil, options), handler)
+}
+
+// registerMethod adds a handler along with its description
+func (rpc *Server) registerMethod(method *Method, handler Handler) {
+	rpc.Handlers[method.Name] = handler
+	rpc.Methods[method.Name] = method
+}
+
+// Dispatch runs the handler for a method on behalf of a request that has
+// already been verified, such as a call inside a batch.  The call goes
+// through the same middleware as a top level request but doesn't consume a
+// sequence number of its own.
+func (rpc *Server) Dispatch(method string, message []byte, parent *RequestContext) (proto.Message, error) {
+	handler := rpc.FindHandler(method)
+	if handler == nil {
+		rpc.Logger.Warn("Could not find handler for method - ", method)
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod)
+	}
+	header := *parent.Header
+	header.Method = method
+	context := &RequestContext{
+		Server:   rpc,
+		Token:    parent.Token,
+		APIToken: parent.APIToken,
+		Header:   &header,
+		Context:  parent.Context,
+		parent:   parent,
+	}
+	return rpc.chain(handler)(rpc, message, context)
 }
@@ -21,30 +21,90 @@ package rpc
 import (
 	"crypto/ed25519"
 	"encoding/hex"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
 )
 
-// CreateSignature creates a signature for a response body
-func (rpc *Server) CreateSignature(response []byte, context *RequestContext) string {
-	signature := ed25519.Sign(context.Token.SignPrivateKey, response)
+// CreateSignature creates a signature for a response envelope
+func (rpc *Server) CreateSignature(envelope *Envelope, context *RequestContext) string {
+	signature := context.Token.Sign(envelope.Bytes())
 
 	sig := hex.EncodeToString([]byte(signature))
 	return sig
 }
 
-// VerifyRequest uses the client's public key to verify the message signature
+// VerifyRequest uses the client's public key to verify the signature of the
+// request envelope & rejects signatures that have already been used
 func (rpc *Server) VerifyRequest(message []byte, sig []byte, context *RequestContext) bool {
 	if context.Token == nil {
 		rpc.Logger.Warn("Request context missing Token when verifying request")
 		return false
 	}
-	if len(context.Token.VerifyPublicKey) == 0 {
-		rpc.Logger.Warn("Verification key is missing")
-		return false
+	return rpc.verifyEnvelope(context.Token.Verify, message, sig, context)
+}
+
+// verifyKeyExchange checks that a key exchange request is signed by the key
+// it carries before its handler may claim the pairing or create a session
+func (rpc *Server) verifyKeyExchange(message []byte, context *RequestContext) error {
+	request := &messages.KeyExchangeRequest{}
+	err := proto.Unmarshal(message, request)
+	if err != nil {
+		rpc.Logger.Warn("Error unmarshaling key exchange request - ", err)
+		return codes.New(codes.ScopeRPC, codes.ErrorDecode)
 	}
-	ok := ed25519.Verify(context.Token.VerifyPublicKey, message, sig)
-	if !ok {
+	if len(request.PublicKey) != ed25519.PublicKeySize {
+		rpc.Logger.Warn("Key exchange request has an invalid public key")
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSignature)
+	}
+	verify := func(envelope []byte, sig []byte) bool {
+		return ed25519.Verify(request.PublicKey, envelope, sig)
+	}
+	if !rpc.verifyEnvelope(verify, message, context.Header.Signature, context) {
+		rpc.Logger.Warn("Message Verification failed")
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSignature)
+	}
+	context.verified = true
+	return nil
+}
+
+// verifyEnvelope checks the signature of a request envelope with the given
+// verifier & rejects signatures that have already been used
+func (rpc *Server) verifyEnvelope(verify func([]byte, []byte) bool, message []byte, sig []byte, context *RequestContext) bool {
+	envelope := &Envelope{
+		Kind:      EnvelopeRequest,
+		Method:    context.Header.Method,
+		Token:     context.Header.Token,
+		Sequence:  context.Header.Sequence,
+		Timestamp: context.Header.Timestamp,
+		Body:      message,
+	}
+	if !verify(envelope.Bytes(), sig) {
 		rpc.Logger.Warn("Request payload could not be verified")
 		return false
 	}
+	if rpc.replays.Add(sig, time.Now(), 2*rpc.MaxClockSkew) {
+		rpc.Logger.Warn("Request signature has already been used")
+		return false
+	}
 	return true
 }
+
+// authenticate verifies the signature of a session request & only then
+// accepts its sequence.  Responses are only signed once a request has been
+// authenticated, since signing advances the session's send sequence.
+func (rpc *Server) authenticate(message []byte, context *RequestContext) error {
+	if !rpc.VerifyRequest(message, context.Header.Signature, context) {
+		rpc.Logger.Warn("Message Verification failed")
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSignature)
+	}
+	if !context.Token.AcceptRecv(context.Header.Sequence) {
+		rpc.Logger.Warn("Message sequence [", context.Header.Sequence, "] has already been used")
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSequence)
+	}
+	context.verified = true
+	return nil
+}
@@ -0,0 +1,262 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+)
+
+// RateLimit is a token bucket refilled at Rate requests per second that can
+// hold up to Burst requests.  A zero rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limits bound the resources a single client can consume
+type Limits struct {
+	// MaxBodySize is the largest request body accepted, both as sent & once
+	// decompressed
+	MaxBodySize int64
+	// ReadHeaderTimeout & ReadTimeout bound how long a client may take to
+	// send its request headers & its whole request
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// IdleTimeout is how long a keep-alive connection may sit unused
+	IdleTimeout time.Duration
+	// Token limits the calls made by each session
+	Token RateLimit
+	// PreAuth limits requests from each remote host that don't belong to a
+	// session
+	PreAuth RateLimit
+	// KeyExchange limits the requests from each remote host that generate
+	// new session keys
+	KeyExchange RateLimit
+}
+
+// DefaultLimits are generous enough for the frontend while keeping a
+// misbehaving client from exhausting memory or CPU
+var DefaultLimits = Limits{
+	MaxBodySize:       8 << 20,
+	ReadHeaderTimeout: 5 * time.Second,
+	ReadTimeout:       30 * time.Second,
+	IdleTimeout:       2 * time.Minute,
+	Token:             RateLimit{Rate: 100, Burst: 200},
+	PreAuth:           RateLimit{Rate: 5, Burst: 20},
+	KeyExchange:       RateLimit{Rate: 1, Burst: 5},
+}
+
+// Reasons a request can be rate limited for
+const (
+	RejectRateLimitedToken       = "rate_limited_token"
+	RejectRateLimitedPreAuth     = "rate_limited_preauth"
+	RejectRateLimitedKeyExchange = "rate_limited_key_exchange"
+)
+
+// keyMethods generate new session keys on every call
+var keyMethods = map[string]bool{
+	"KeyExchange": true,
+	"Rekey":       true,
+}
+
+// bucketIdleTime is how long an unused bucket is kept before being pruned
+const bucketIdleTime = 10 * time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a token bucket for each key
+type rateLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	limiter := &rateLimiter{
+		buckets: make(map[string]*bucket),
+	}
+	return limiter
+}
+
+// allow takes a token from a key's bucket.  When the bucket is empty the time
+// until the next token is available is returned instead.
+func (limiter *rateLimiter) allow(key string, limit RateLimit, now time.Time) (bool, time.Duration) {
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	burst := math.Max(float64(limit.Burst), 1)
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if now.Sub(limiter.pruned) > bucketIdleTime {
+		limiter.prune(now)
+	}
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		limiter.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune forgets buckets that haven't been used recently.  They would have
+// refilled by now anyway.
+func (limiter *rateLimiter) prune(now time.Time) {
+	for key, b := range limiter.buckets {
+		if now.Sub(b.updated) > bucketIdleTime {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.pruned = now
+}
+
+// remoteHost is the key used to limit requests that don't belong to a
+// session.  Every connection to a unix socket shares the same key.
+func remoteHost(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// admitPreAuth applies the pre-auth limit to a request that doesn't belong to
+// a known session.  This happens before the request headers are verified, so
+// floods of unauthenticated requests are turned away as cheaply as possible.
+// Key exchange requests are limited by admitKeyExchange once their body has
+// been read.
+func (rpc *Server) admitPreAuth(resp http.ResponseWriter, req *http.Request, context *RequestContext) bool {
+	method := req.Header.Get("Request-Method")
+	if method == "KeyExchange" {
+		return true
+	}
+	if _, ok := rpc.Sessions.Get(req.Header.Get("Client-Token")); ok {
+		return true
+	}
+
+	host := remoteHost(req)
+	if ok, wait := rpc.limiter.allow("preauth:"+host, rpc.Limits.PreAuth, time.Now()); !ok {
+		rpc.Logger.Warn("Pre-auth rate limit exceeded for [", host, "]")
+		rpc.rateLimited(resp, context, RejectRateLimitedPreAuth, wait)
+		return false
+	}
+	return true
+}
+
+// admitKeyExchange applies the pre-auth & key exchange limits to a key
+// exchange request.  Every local process shares the same remote host, so a
+// request carrying a valid bootstrap proof is let through, otherwise any
+// local process could use up the launcher's limit.  The proof is checked with
+// a single HMAC, which is cheap next to the key generation the limits guard.
+func (rpc *Server) admitKeyExchange(resp http.ResponseWriter, req *http.Request, message []byte, context *RequestContext) bool {
+	request := &messages.KeyExchangeRequest{}
+	if proto.Unmarshal(message, request) == nil && rpc.Pairing.Proven(request.PublicKey, request.BootstrapProof) {
+		return true
+	}
+
+	host := remoteHost(req)
+	now := time.Now()
+	if ok, wait := rpc.limiter.allow("preauth:"+host, rpc.Limits.PreAuth, now); !ok {
+		rpc.Logger.Warn("Pre-auth rate limit exceeded for [", host, "]")
+		rpc.rateLimited(resp, context, RejectRateLimitedPreAuth, wait)
+		return false
+	}
+	if ok, wait := rpc.limiter.allow("kex:"+host, rpc.Limits.KeyExchange, now); !ok {
+		rpc.Logger.Warn("Key exchange rate limit exceeded for [", host, "]")
+		rpc.rateLimited(resp, context, RejectRateLimitedKeyExchange, wait)
+		return false
+	}
+	return true
+}
+
+// admitToken applies the session limits to a request once its headers have
+// been checked.  The request hasn't been authenticated yet, so the rejection
+// is unsigned & the request's sequence isn't used up.
+func (rpc *Server) admitToken(resp http.ResponseWriter, context *RequestContext) bool {
+	if context.Token == nil {
+		return true
+	}
+	now := time.Now()
+	token := context.Token.Token
+	if ok, wait := rpc.limiter.allow("token:"+token, rpc.Limits.Token, now); !ok {
+		rpc.Logger.Warn("Rate limit exceeded for method [", context.Header.Method, "]")
+		rpc.rateLimited(resp, context, RejectRateLimitedToken, wait)
+		return false
+	}
+	if keyMethods[context.Header.Method] {
+		if ok, wait := rpc.limiter.allow("kex:"+token, rpc.Limits.KeyExchange, now); !ok {
+			rpc.Logger.Warn("Key exchange rate limit exceeded for method [", context.Header.Method, "]")
+			rpc.rateLimited(resp, context, RejectRateLimitedKeyExchange, wait)
+			return false
+		}
+	}
+	return true
+}
+
+// rateLimited rejects a request, telling the client when it may try again
+func (rpc *Server) rateLimited(resp http.ResponseWriter, context *RequestContext, reason string, wait time.Duration) {
+	resp.Header().Set("Retry-After", retryAfter(wait))
+	rpc.Rejections.Add(reason)
+	rpc.WriteError(resp, context, codes.New(codes.ScopeRPC, codes.ErrorRateLimited).WithRetry(wait))
+}
+
+// retryAfter formats a wait as the whole seconds of a Retry-After header
+func retryAfter(wait time.Duration) string {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// admitBody refuses a request whose declared body is too large & caps how
+// much of the body will be read when no length was declared
+func (rpc *Server) admitBody(resp http.ResponseWriter, req *http.Request, context *RequestContext) bool {
+	if rpc.Limits.MaxBodySize <= 0 {
+		return true
+	}
+	if req.ContentLength > rpc.Limits.MaxBodySize {
+		rpc.Logger.Warn("Request body of [", req.ContentLength, "] bytes is too large")
+		rpc.reject(resp, context, codes.New(codes.ScopeRPC, codes.ErrorBodyTooLarge))
+		return false
+	}
+	req.Body = http.MaxBytesReader(resp, req.Body, rpc.Limits.MaxBodySize)
+	return true
+}
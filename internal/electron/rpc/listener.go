@@ -0,0 +1,159 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UnixScheme prefixes listener addresses that name a unix domain socket.
+// `unix:///path/to/socket` is a socket file & `unix:@name` is a socket in
+// the Linux abstract namespace.
+const UnixScheme = "unix:"
+
+// UnixSocketMode is the permission given to socket files so only the owning
+// user can connect
+const UnixSocketMode = 0600
+
+// ParseUnixAddress extracts the socket path from a unix listener address.
+// Abstract socket paths keep their leading @.
+func ParseUnixAddress(address string) (string, bool) {
+	if !strings.HasPrefix(address, UnixScheme) {
+		return "", false
+	}
+	path := strings.TrimPrefix(address, UnixScheme)
+	if strings.HasPrefix(path, "//") {
+		path = strings.TrimPrefix(path, "//")
+		if !strings.HasPrefix(path, "/") {
+			return "", false
+		}
+		return path, true
+	}
+	if strings.HasPrefix(path, "@") && len(path) > 1 {
+		return path, true
+	}
+	return "", false
+}
+
+// IsUnixAddress checks whether a listener address names a unix domain socket
+func IsUnixAddress(address string) bool {
+	_, ok := ParseUnixAddress(address)
+	return ok
+}
+
+// Listen opens a listener for a TCP or unix socket address.
+// Connections to unix sockets from other users are refused where the peer's
+// credentials can be checked, which is the only access control abstract
+// sockets have.
+func Listen(logger *logrus.Logger, address string) (net.Listener, error) {
+	path, ok := ParseUnixAddress(address)
+	if !ok {
+		if strings.HasPrefix(address, UnixScheme) {
+			return nil, fmt.Errorf("invalid unix socket address [%s]", address)
+		}
+		return net.Listen("tcp", address)
+	}
+
+	abstract := strings.HasPrefix(path, "@")
+	if !abstract {
+		err := removeStaleSocket(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conn, err := listenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	// the socket was created with these permissions where the umask can be
+	// set, this makes sure of them everywhere else
+	if !abstract {
+		err = os.Chmod(path, UnixSocketMode)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error setting socket permissions - %w", err)
+		}
+	}
+	listener := &peerListener{
+		Listener: conn,
+		logger:   logger,
+		uid:      os.Getuid(),
+	}
+	return listener, nil
+}
+
+// removeStaleSocket removes a socket file left behind by a previous run.
+// Sockets that still accept connections belong to a running service & are
+// left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("[%s] exists & is not a socket", path)
+	}
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("socket [%s] is already in use", path)
+	}
+	return os.Remove(path)
+}
+
+// peerListener refuses unix socket connections from other users
+type peerListener struct {
+	net.Listener
+	logger *logrus.Logger
+	uid    int
+}
+
+// Accept waits for the next connection from the owning user.
+// Where peer credentials are supported, a connection whose credentials can't
+// be looked up is refused rather than trusted.
+func (listener *peerListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := listener.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		uid, ok := peerUID(conn)
+		if !ok && !peerCredentials {
+			return conn, nil
+		}
+		if ok && uid == listener.uid {
+			return conn, nil
+		}
+		if ok {
+			listener.logger.Warn("Refused unix socket connection from uid [", uid, "]")
+		} else {
+			listener.logger.Warn("Refused unix socket connection, unable to look up peer credentials")
+		}
+		conn.Close()
+	}
+}
@@ -19,16 +19,37 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package rpc
 
 import (
+	"time"
+
 	"github.com/farrcraft/brewtheory/internal/electron/codes"
 	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
 )
 
-// SetInternalError sets an error in a response header
+// SetInternalError sets an error in a response header.
+// The cause of a wrapped error isn't included, only its code & details.
 func SetInternalError(header *messages.ResponseHeader, err error) {
 	code := codes.ToInternalError(err)
 	header.Code = int32(code.Code)
 	header.Scope = int32(code.Scope)
-	header.Status = code.Error()
+	header.Status = code.Message
+	header.Type = int32(code.Type)
+	header.Key = code.Key
+	header.Violations = nil
+	for _, violation := range code.Violations {
+		header.Violations = append(header.Violations, &messages.FieldViolation{
+			Field:       violation.Field,
+			Key:         violation.Key,
+			Description: violation.Description,
+		})
+	}
+	header.Entities = nil
+	for _, entity := range code.Entities {
+		header.Entities = append(header.Entities, &messages.EntityReference{
+			Type: entity.Type,
+			Id:   entity.ID,
+		})
+	}
+	header.RetryAfterMs = code.RetryAfter.Milliseconds()
 }
 
 // SetRPCError sets an rpc-specific error in a response header
@@ -36,6 +57,34 @@ func SetRPCError(header *messages.ResponseHeader, c codes.Code) {
 	header.Code = int32(c)
 	header.Scope = int32(codes.ScopeRPC)
 	header.Status = codes.StatusSystemError
+	header.Type = int32(codes.TypeSystem)
+	header.Key = codes.KeyFromCode(c)
+}
+
+// HeaderError converts the error in a response header back into an
+// InternalError.  Nil is returned when the header doesn't carry an error.
+func HeaderError(header *messages.ResponseHeader) *codes.InternalError {
+	if header == nil || codes.Code(header.Code) == codes.ErrorOK {
+		return nil
+	}
+	err := codes.New(codes.Scope(header.Scope), codes.Code(header.Code))
+	if header.Status != "" && header.Status != codes.StatusSystemError && header.Status != codes.StatusAppError {
+		err.Message = header.Status
+	}
+	if codes.ErrorType(header.Type) == codes.TypeApplication || header.Status == codes.StatusAppError {
+		err.Type = codes.TypeApplication
+	}
+	if header.Key != "" {
+		err.Key = header.Key
+	}
+	for _, violation := range header.Violations {
+		err.WithField(violation.Field, violation.Key, violation.Description)
+	}
+	for _, entity := range header.Entities {
+		err.WithEntity(entity.Type, entity.Id)
+	}
+	err.RetryAfter = time.Duration(header.RetryAfterMs) * time.Millisecond
+	return err
 }
 
 // NewResponseHeader creates a new response header
@@ -0,0 +1,195 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+)
+
+// DefaultMethodVersion is the version of a method registered without one
+const DefaultMethodVersion = 1
+
+// Method describes a registered RPC method
+type Method struct {
+	Name string
+	// Request & Response are nil for methods registered without a typed handler
+	Request  protoreflect.MessageDescriptor
+	Response protoreflect.MessageDescriptor
+	// Version is bumped whenever a method changes incompatibly
+	Version int32
+	// SessionOnly methods act on the caller's signed session, so they aren't
+	// served by the JSON gateway
+	SessionOnly bool
+	// Mutating methods change state, so calls to them are audited
+	Mutating bool
+	// Reversible methods register an undo for every change they make, so
+	// they may be part of an atomic batch
+	Reversible bool
+}
+
+// MethodOption changes the description of a method as it is registered
+type MethodOption func(*Method)
+
+// Version sets the version of a method
+func Version(version int32) MethodOption {
+	return func(method *Method) {
+		method.Version = version
+	}
+}
+
+// SessionOnly marks a method as needing a signed session
+func SessionOnly() MethodOption {
+	return func(method *Method) {
+		method.SessionOnly = true
+	}
+}
+
+// Mutating marks a method as changing state
+func Mutating() MethodOption {
+	return func(method *Method) {
+		method.Mutating = true
+	}
+}
+
+// Reversible marks a method as able to roll back its changes
+func Reversible() MethodOption {
+	return func(method *Method) {
+		method.Reversible = true
+	}
+}
+
+// newMethod creates the description of a method
+func newMethod(name string, request protoreflect.MessageDescriptor, response protoreflect.MessageDescriptor, options []MethodOption) *Method {
+	method := &Method{
+		Name:     name,
+		Request:  request,
+		Response: response,
+		Version:  DefaultMethodVersion,
+	}
+	for _, option := range options {
+		option(method)
+	}
+	return method
+}
+
+// Catalog describes every registered method along with the schema of every
+// message & enum type they use, sorted by name
+func (rpc *Server) Catalog() *messages.ListMethodsResponse {
+	catalog := &messages.ListMethodsResponse{}
+	schemas := &schemaSet{
+		types: make(map[protoreflect.FullName]*messages.MessageSchema),
+		enums: make(map[protoreflect.FullName]*messages.EnumSchema),
+	}
+
+	for _, method := range rpc.Methods {
+		schema := &messages.MethodSchema{
+			Name:        method.Name,
+			Version:     method.Version,
+			SessionOnly: method.SessionOnly,
+			Mutating:    method.Mutating,
+		}
+		if method.Request != nil {
+			schema.Request = string(method.Request.FullName())
+			schemas.addMessage(method.Request)
+		}
+		if method.Response != nil {
+			schema.Response = string(method.Response.FullName())
+			schemas.addMessage(method.Response)
+		}
+		catalog.Methods = append(catalog.Methods, schema)
+	}
+	sort.Slice(catalog.Methods, func(i, j int) bool {
+		return catalog.Methods[i].Name < catalog.Methods[j].Name
+	})
+
+	for _, schema := range schemas.types {
+		catalog.Messages = append(catalog.Messages, schema)
+	}
+	sort.Slice(catalog.Messages, func(i, j int) bool {
+		return catalog.Messages[i].Name < catalog.Messages[j].Name
+	})
+	for _, schema := range schemas.enums {
+		catalog.Enums = append(catalog.Enums, schema)
+	}
+	sort.Slice(catalog.Enums, func(i, j int) bool {
+		return catalog.Enums[i].Name < catalog.Enums[j].Name
+	})
+	return catalog
+}
+
+// schemaSet collects the schema of every type reachable from a message
+type schemaSet struct {
+	types map[protoreflect.FullName]*messages.MessageSchema
+	enums map[protoreflect.FullName]*messages.EnumSchema
+}
+
+func (set *schemaSet) addMessage(descriptor protoreflect.MessageDescriptor) {
+	if _, ok := set.types[descriptor.FullName()]; ok {
+		return
+	}
+	schema := &messages.MessageSchema{
+		Name: string(descriptor.FullName()),
+		File: descriptor.ParentFile().Path(),
+	}
+	// added before recursing so self referencing messages terminate
+	set.types[descriptor.FullName()] = schema
+
+	fields := descriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		field := &messages.FieldSchema{
+			Name:     string(fd.Name()),
+			Number:   int32(fd.Number()),
+			Kind:     fd.Kind().String(),
+			Repeated: fd.IsList(),
+		}
+		switch {
+		case fd.Message() != nil:
+			field.TypeName = string(fd.Message().FullName())
+			set.addMessage(fd.Message())
+		case fd.Enum() != nil:
+			field.TypeName = string(fd.Enum().FullName())
+			set.addEnum(fd.Enum())
+		}
+		schema.Fields = append(schema.Fields, field)
+	}
+}
+
+func (set *schemaSet) addEnum(descriptor protoreflect.EnumDescriptor) {
+	if _, ok := set.enums[descriptor.FullName()]; ok {
+		return
+	}
+	schema := &messages.EnumSchema{
+		Name: string(descriptor.FullName()),
+		File: descriptor.ParentFile().Path(),
+	}
+	values := descriptor.Values()
+	for i := 0; i < values.Len(); i++ {
+		value := values.Get(i)
+		schema.Values = append(schema.Values, &messages.EnumValueSchema{
+			Name:   string(value.Name()),
+			Number: int32(value.Number()),
+		})
+	}
+	set.enums[descriptor.FullName()] = schema
+}
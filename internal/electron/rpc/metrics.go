@@ -0,0 +1,298 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// MetricsPath is where the metrics endpoint serves the metrics
+const MetricsPath = "/metrics"
+
+// ContentTypeMetrics is the Prometheus text exposition format
+const ContentTypeMetrics = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the handler
+// latency histogram buckets
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics records how long each method takes to handle & the codes it
+// returns
+type Metrics struct {
+	Buckets []float64
+	Started time.Time
+
+	mutex   sync.Mutex
+	methods map[string]*histogram
+	results map[result]uint64
+}
+
+// histogram counts observations into cumulative buckets
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// result identifies a response code counter
+type result struct {
+	method string
+	code   codes.Code
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	metrics := &Metrics{
+		Buckets: DefaultLatencyBuckets,
+		Started: time.Now(),
+		methods: make(map[string]*histogram),
+		results: make(map[result]uint64),
+	}
+	return metrics
+}
+
+// Observe records a handled call
+func (metrics *Metrics) Observe(method string, code codes.Code, duration time.Duration) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	latency, ok := metrics.methods[method]
+	if !ok {
+		latency = &histogram{counts: make([]uint64, len(metrics.Buckets))}
+		metrics.methods[method] = latency
+	}
+	seconds := duration.Seconds()
+	for i, bound := range metrics.Buckets {
+		if seconds <= bound {
+			latency.counts[i]++
+		}
+	}
+	latency.sum += seconds
+	latency.count++
+	metrics.results[result{method: method, code: code}]++
+}
+
+// Instrument is middleware that records the latency & result code of every
+// call, including calls made inside a batch
+func Instrument() Middleware {
+	return func(next Handler) Handler {
+		return func(server *Server, message []byte, context *RequestContext) (proto.Message, error) {
+			start := time.Now()
+			response, err := next(server, message, context)
+			code := codes.ErrorOK
+			if err != nil {
+				code = codes.ToInternalError(err).Code
+			} else if header := responseHeader(response); header != nil {
+				code = codes.Code(header.Code)
+			}
+			server.Metrics.Observe(context.Header.Method, code, time.Since(start))
+			return response, err
+		}
+	}
+}
+
+// WriteMetrics writes the current metrics in the Prometheus text format
+func (rpc *Server) WriteMetrics(out io.Writer) error {
+	writer := bufio.NewWriter(out)
+	metrics := rpc.Metrics
+
+	metrics.mutex.Lock()
+	methods := make([]string, 0, len(metrics.methods))
+	for method := range metrics.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	writeHeader(writer, "brewtheory_rpc_duration_seconds", "histogram", "Time taken to handle RPC calls")
+	for _, method := range methods {
+		latency := metrics.methods[method]
+		for i, bound := range metrics.Buckets {
+			fmt.Fprintf(writer, "brewtheory_rpc_duration_seconds_bucket{method=%s,le=\"%g\"} %d\n", label(method), bound, latency.counts[i])
+		}
+		fmt.Fprintf(writer, "brewtheory_rpc_duration_seconds_bucket{method=%s,le=\"+Inf\"} %d\n", label(method), latency.count)
+		fmt.Fprintf(writer, "brewtheory_rpc_duration_seconds_sum{method=%s} %g\n", label(method), latency.sum)
+		fmt.Fprintf(writer, "brewtheory_rpc_duration_seconds_count{method=%s} %d\n", label(method), latency.count)
+	}
+
+	results := make([]result, 0, len(metrics.results))
+	for key := range metrics.results {
+		results = append(results, key)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].method != results[j].method {
+			return results[i].method < results[j].method
+		}
+		return results[i].code < results[j].code
+	})
+	writeHeader(writer, "brewtheory_rpc_responses_total", "counter", "RPC calls handled by method & result code")
+	for _, key := range results {
+		fmt.Fprintf(writer, "brewtheory_rpc_responses_total{method=%s,code=%s} %d\n", label(key.method), label(codes.KeyFromCode(key.code)), metrics.results[key])
+	}
+	started := metrics.Started
+	metrics.mutex.Unlock()
+
+	rejections := rpc.Rejections.Counts()
+	reasons := make([]string, 0, len(rejections))
+	for reason := range rejections {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	writeHeader(writer, "brewtheory_rpc_rejected_total", "counter", "Requests turned away before reaching a handler by reason")
+	for _, reason := range reasons {
+		fmt.Fprintf(writer, "brewtheory_rpc_rejected_total{reason=%s} %d\n", label(reason), rejections[reason])
+	}
+
+	writeHeader(writer, "brewtheory_sessions", "gauge", "Active client sessions")
+	fmt.Fprintf(writer, "brewtheory_sessions %d\n", rpc.Sessions.Count())
+
+	if rpc.Jobs != nil {
+		statuses := make(map[string]int)
+		for _, job := range rpc.Jobs.List() {
+			statuses[string(job.Status)]++
+		}
+		names := make([]string, 0, len(statuses))
+		for status := range statuses {
+			names = append(names, status)
+		}
+		sort.Strings(names)
+		writeHeader(writer, "brewtheory_jobs", "gauge", "Background jobs by status")
+		for _, status := range names {
+			fmt.Fprintf(writer, "brewtheory_jobs{status=%s} %d\n", label(status), statuses[status])
+		}
+	}
+
+	writeHeader(writer, "brewtheory_start_time_seconds", "gauge", "Unix time the service started")
+	fmt.Fprintf(writer, "brewtheory_start_time_seconds %d\n", started.Unix())
+	writeRuntimeMetrics(writer)
+	return writer.Flush()
+}
+
+// writeRuntimeMetrics writes the Go runtime's goroutine, memory & garbage
+// collector stats
+func writeRuntimeMetrics(writer io.Writer) {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	writeHeader(writer, "go_info", "gauge", "Go version the service was built with")
+	fmt.Fprintf(writer, "go_info{version=%s} 1\n", label(runtime.Version()))
+	writeHeader(writer, "go_goroutines", "gauge", "Goroutines that currently exist")
+	fmt.Fprintf(writer, "go_goroutines %d\n", runtime.NumGoroutine())
+	writeHeader(writer, "go_memstats_heap_alloc_bytes", "gauge", "Heap bytes allocated & still in use")
+	fmt.Fprintf(writer, "go_memstats_heap_alloc_bytes %d\n", memory.HeapAlloc)
+	writeHeader(writer, "go_memstats_heap_inuse_bytes", "gauge", "Heap bytes in in-use spans")
+	fmt.Fprintf(writer, "go_memstats_heap_inuse_bytes %d\n", memory.HeapInuse)
+	writeHeader(writer, "go_memstats_sys_bytes", "gauge", "Bytes obtained from the system")
+	fmt.Fprintf(writer, "go_memstats_sys_bytes %d\n", memory.Sys)
+	writeHeader(writer, "go_memstats_mallocs_total", "counter", "Heap objects allocated")
+	fmt.Fprintf(writer, "go_memstats_mallocs_total %d\n", memory.Mallocs)
+	writeHeader(writer, "go_gc_cycles_total", "counter", "Completed garbage collection cycles")
+	fmt.Fprintf(writer, "go_gc_cycles_total %d\n", memory.NumGC)
+	writeHeader(writer, "go_gc_pause_seconds_total", "counter", "Time spent in garbage collection pauses")
+	fmt.Fprintf(writer, "go_gc_pause_seconds_total %g\n", time.Duration(memory.PauseTotalNs).Seconds())
+}
+
+func writeHeader(writer io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// label quotes a label value, escaping as the text format requires
+func label(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+// ServeMetrics starts serving the metrics endpoint on a loopback address.
+// The returned server is shut down by the caller.
+func (rpc *Server) ServeMetrics(address string) (*http.Server, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("metrics address [%s] is not a loopback address", address)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(MetricsPath, rpc.serveMetrics)
+	writer := rpc.Logger.Writer()
+	server := &http.Server{
+		Handler:           mux,
+		ErrorLog:          log.New(writer, "", 0),
+		ReadHeaderTimeout: rpc.Limits.ReadHeaderTimeout,
+		ReadTimeout:       rpc.Limits.ReadTimeout,
+		IdleTimeout:       rpc.Limits.IdleTimeout,
+	}
+	go func() {
+		// the log pipe is closed once the caller shuts the server down
+		defer writer.Close()
+		err := server.Serve(listener)
+		if !errors.Is(err, http.ErrServerClosed) {
+			rpc.Logger.Error("Metrics server error - ", err)
+		}
+	}()
+	rpc.Logger.Info("Metrics listening on [http://", listener.Addr(), MetricsPath, "]")
+	return server, nil
+}
+
+// serveMetrics handles scrapes of the metrics endpoint
+func (rpc *Server) serveMetrics(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp.Header().Set("Allow", "GET, HEAD")
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil || !isLoopback(host) {
+		rpc.Logger.Warn("Refusing metrics request from [", req.RemoteAddr, "]")
+		http.Error(resp, "forbidden", http.StatusForbidden)
+		return
+	}
+	resp.Header().Set("Content-Type", ContentTypeMetrics)
+	err = rpc.WriteMetrics(resp)
+	if err != nil {
+		rpc.Logger.Warn("Error writing metrics - ", err)
+	}
+}
+
+// isLoopback reports whether a host names the local machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,188 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+)
+
+// Middleware wraps a Handler with additional behavior
+type Middleware func(next Handler) Handler
+
+// Policy decides whether a request may invoke a method.
+// Returning an error denies the request & the error is sent to the client.
+type Policy func(*Server, *RequestContext) error
+
+// DefaultRedactedFields are the message fields that are never logged
+var DefaultRedactedFields = []string{"publicKey", "token", "signature"}
+
+// Use appends middleware to the chain wrapped around every handler.
+// The first middleware added is the outermost.
+func (rpc *Server) Use(middleware ...Middleware) {
+	rpc.Middleware = append(rpc.Middleware, middleware...)
+}
+
+// chain wraps a handler with all of the registered middleware
+func (rpc *Server) chain(handler Handler) Handler {
+	for i := len(rpc.Middleware) - 1; i >= 0; i-- {
+		handler = rpc.Middleware[i](handler)
+	}
+	return handler
+}
+
+// errorResponse creates a response carrying only an error header.
+// Every response message embeds its header as field 1, so the client can
+// decode this as whatever response type it was expecting.
+func errorResponse(err error) proto.Message {
+	response := &messages.EmptyResponse{
+		Header: NewResponseHeader(),
+	}
+	SetInternalError(response.Header, err)
+	return response
+}
+
+// Recover converts a panicking handler into an internal error response
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(server *Server, message []byte, context *RequestContext) (response proto.Message, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					server.Logger.Error("Panic in handler [", context.Header.Method, "] - ", r, "\n", string(debug.Stack()))
+					response = errorResponse(codes.New(codes.ScopeRPC, codes.ErrorPanic))
+					err = nil
+				}
+			}()
+			return next(server, message, context)
+		}
+	}
+}
+
+// Timing logs how long each handler takes to run
+func Timing() Middleware {
+	return func(next Handler) Handler {
+		return func(server *Server, message []byte, context *RequestContext) (proto.Message, error) {
+			start := time.Now()
+			response, err := next(server, message, context)
+			server.Logger.WithFields(logrus.Fields{
+				"method":   context.Header.Method,
+				"duration": time.Since(start).String(),
+			}).Debug("Handled RPC request")
+			return response, err
+		}
+	}
+}
+
+// Logging writes requests & responses to the debug log.
+// Fields with any of the given names are cleared before the message is
+// logged.  Request bodies are logged by size only since their type is not
+// known until the handler decodes them.
+func Logging(redacted ...string) Middleware {
+	fields := make(map[protoreflect.Name]bool, len(redacted))
+	for _, name := range redacted {
+		fields[protoreflect.Name(name)] = true
+	}
+	return func(next Handler) Handler {
+		return func(server *Server, message []byte, context *RequestContext) (proto.Message, error) {
+			if !server.Logger.IsLevelEnabled(logrus.DebugLevel) {
+				return next(server, message, context)
+			}
+			server.Logger.WithFields(logrus.Fields{
+				"method": context.Header.Method,
+				"size":   len(message),
+			}).Debug("RPC request")
+
+			response, err := next(server, message, context)
+			if response != nil {
+				logged := proto.Clone(response)
+				redact(logged.ProtoReflect(), fields)
+				server.Logger.WithFields(logrus.Fields{
+					"method":   context.Header.Method,
+					"response": prototext.MarshalOptions{}.Format(logged),
+				}).Debug("RPC response")
+			}
+			return response, err
+		}
+	}
+}
+
+// redact recursively clears the named fields of a message
+func redact(message protoreflect.Message, fields map[protoreflect.Name]bool) {
+	message.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if fields[fd.Name()] {
+			message.Clear(fd)
+			return true
+		}
+		if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				redact(list.Get(i).Message(), fields)
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				value.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					redact(v.Message(), fields)
+					return true
+				})
+			}
+		default:
+			redact(value.Message(), fields)
+		}
+		return true
+	})
+}
+
+// Authorize applies a per-method policy before the handler is invoked.
+// Methods without a policy are allowed.
+func Authorize(policies map[string]Policy) Middleware {
+	return func(next Handler) Handler {
+		return func(server *Server, message []byte, context *RequestContext) (proto.Message, error) {
+			policy, ok := policies[context.Header.Method]
+			if ok {
+				err := policy(server, context)
+				if err != nil {
+					server.Logger.Warn("Request for method [", context.Header.Method, "] denied by policy")
+					return errorResponse(err), nil
+				}
+			}
+			return next(server, message, context)
+		}
+	}
+}
+
+// RequireToken is a policy that only allows clients that have completed a
+// key exchange or authenticated with an API token
+func RequireToken(server *Server, context *RequestContext) error {
+	if context.Token == nil && context.APIToken == nil {
+		return codes.New(codes.ScopeRPC, codes.ErrorUnauthorized)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// MinBootstrapSecretSize is the smallest bootstrap secret that is accepted
+const MinBootstrapSecretSize = 16
+
+// Pairing decides which clients may complete a key exchange.
+// The launching process shares a bootstrap secret with the service at
+// startup & proves it knows the secret in its key exchange request, so other
+// local processes that can reach the listener can't pair.
+type Pairing struct {
+	Logger *logrus.Logger
+	// Lock refuses every key exchange after the first one succeeds
+	Lock bool
+
+	secret []byte
+	mutex  sync.Mutex
+	paired bool
+	// a key exchange holds the pairing while it is in progress
+	claimed bool
+}
+
+// NewPairing creates a pairing guard.
+// Without a secret any client may pair.
+func NewPairing(logger *logrus.Logger, secret []byte, lock bool) *Pairing {
+	pairing := &Pairing{
+		Logger: logger,
+		Lock:   lock,
+		secret: secret,
+	}
+	return pairing
+}
+
+// Required checks whether clients must prove the bootstrap secret
+func (pairing *Pairing) Required() bool {
+	return len(pairing.secret) != 0
+}
+
+// BootstrapProof computes the proof a client sends for its public key
+func BootstrapProof(secret []byte, publicKey []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(publicKey)
+	return mac.Sum(nil)
+}
+
+// Proven checks whether a key exchange carries a valid bootstrap proof.
+// It is always false when no secret is configured.
+func (pairing *Pairing) Proven(publicKey []byte, proof []byte) bool {
+	if len(pairing.secret) == 0 {
+		return false
+	}
+	return hmac.Equal(proof, BootstrapProof(pairing.secret, publicKey))
+}
+
+// Claim checks that a key exchange may proceed & holds the pairing for it.
+// Checking & holding happen together so concurrent key exchanges can't both
+// slip past a pairing lock.  The claim must be followed by Commit once the
+// session has been created, or by Release if the key exchange fails.
+func (pairing *Pairing) Claim(publicKey []byte, proof []byte) error {
+	pairing.mutex.Lock()
+	defer pairing.mutex.Unlock()
+
+	if pairing.Lock && (pairing.paired || pairing.claimed) {
+		pairing.Logger.Warn("Key exchange refused, pairing is locked")
+		return codes.New(codes.ScopeRPC, codes.ErrorPairingLocked)
+	}
+	if len(pairing.secret) != 0 && !pairing.Proven(publicKey, proof) {
+		pairing.Logger.Warn("Key exchange refused, bad bootstrap proof")
+		return codes.New(codes.ScopeRPC, codes.ErrorBadBootstrapProof)
+	}
+	pairing.claimed = true
+	return nil
+}
+
+// Commit records that a claimed key exchange succeeded
+func (pairing *Pairing) Commit() {
+	pairing.mutex.Lock()
+	defer pairing.mutex.Unlock()
+
+	if pairing.Lock && !pairing.paired {
+		pairing.Logger.Info("Pairing locked after first key exchange")
+	}
+	pairing.claimed = false
+	pairing.paired = true
+}
+
+// Release gives up the claim of a key exchange that failed, so a failure
+// doesn't lock the launching process out
+func (pairing *Pairing) Release() {
+	pairing.mutex.Lock()
+	defer pairing.mutex.Unlock()
+	pairing.claimed = false
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import "net"
+
+// peerCredentials is set where the user on the other end of a unix socket
+// can be looked up
+const peerCredentials = false
+
+// peerUID can't look up peer credentials on this platform, so unix sockets
+// rely on their file permissions alone
+func peerUID(conn net.Conn) (int, bool) {
+	return 0, false
+}
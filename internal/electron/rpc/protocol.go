@@ -0,0 +1,82 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// Protocol versions
+// ProtocolVersion is bumped whenever the wire format changes.  Clients older
+// than MinProtocolVersion are refused during key exchange.
+const (
+	ProtocolVersion    int32 = 1
+	MinProtocolVersion int32 = 1
+	// LegacyProtocolVersion is assumed for clients that don't send a version
+	LegacyProtocolVersion int32 = 1
+)
+
+// Capabilities are optional features agreed on during key exchange
+const (
+	CapabilityBatch         = "batch"
+	CapabilityEvents        = "events"
+	CapabilityIntrospection = "introspection"
+)
+
+// DefaultCapabilities are the capabilities a server offers
+var DefaultCapabilities = []string{
+	CapabilityBatch,
+	CapabilityEvents,
+	CapabilityIntrospection,
+}
+
+// Negotiate picks the protocol version & capabilities for a new session.
+// The session speaks the older of the two protocol versions & gets the
+// capabilities that both sides support.
+func (rpc *Server) Negotiate(version int32, capabilities []string) (int32, []string, error) {
+	if version == 0 {
+		version = LegacyProtocolVersion
+	}
+	if version < MinProtocolVersion {
+		rpc.Logger.Warn("Client protocol version [", version, "] is older than the minimum [", MinProtocolVersion, "]")
+		return 0, nil, codes.New(codes.ScopeRPC, codes.ErrorIncompatibleProtocol)
+	}
+	if version > ProtocolVersion {
+		version = ProtocolVersion
+	}
+
+	offered := make(map[string]bool, len(rpc.Capabilities))
+	for _, capability := range rpc.Capabilities {
+		offered[capability] = true
+	}
+	var agreed []string
+	for _, capability := range capabilities {
+		if offered[capability] {
+			agreed = append(agreed, capability)
+			// a capability listed twice is only agreed once
+			offered[capability] = false
+		}
+	}
+	return version, agreed, nil
+}
+
+// HasCapability reports whether the client negotiated a capability
+func (context *RequestContext) HasCapability(capability string) bool {
+	return context.Token != nil && context.Token.HasCapability(capability)
+}
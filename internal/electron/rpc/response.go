@@ -0,0 +1,111 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// HTTPStatus maps an error code to the HTTP status used when the error is
+// reported by the transport.  Errors reported by handlers are always sent
+// with a 200 status since the request itself was delivered successfully.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.ErrorOK:
+		return http.StatusOK
+	case codes.ErrorBadRequest, codes.ErrorDecode, codes.ErrorInvalidRequest:
+		return http.StatusBadRequest
+	case codes.ErrorUnsupportedVerb:
+		return http.StatusMethodNotAllowed
+	case codes.ErrorUnknownPath, codes.ErrorUnknownMethod:
+		return http.StatusNotFound
+	case codes.ErrorUnauthenticated, codes.ErrorBadSignature, codes.ErrorBadTimestamp, codes.ErrorBadBootstrapProof:
+		return http.StatusUnauthorized
+	case codes.ErrorUnauthorized, codes.ErrorPairingLocked:
+		return http.StatusForbidden
+	case codes.ErrorBadSequence:
+		return http.StatusConflict
+	case codes.ErrorUnsupportedEncoding:
+		return http.StatusUnsupportedMediaType
+	case codes.ErrorBodyTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case codes.ErrorRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteError sends a response carrying only an error header.
+// The HTTP status is derived from the error code.
+func (rpc *Server) WriteError(resp http.ResponseWriter, context *RequestContext, err error) {
+	internal := codes.ToInternalError(err)
+	rpc.WriteMessage(resp, context, HTTPStatus(internal.Code), errorResponse(internal))
+}
+
+// WriteMessage encodes & sends a response message.
+// When the request has been authenticated as coming from a known client the
+// response is signed & sequenced.  Otherwise it is sent unsigned, either
+// because there is no key to sign it with or because anyone who has seen a
+// session token could otherwise use up the session's sequence numbers.
+// Clients should only trust unsigned responses as far as knowing that their
+// request was refused.
+func (rpc *Server) WriteMessage(resp http.ResponseWriter, context *RequestContext, status int, message proto.Message) {
+	responseData, err := proto.Marshal(message)
+	if err != nil {
+		rpc.Logger.Warn("Error marshaling response - ", err)
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// set response headers
+	if context.Token != nil && context.verified {
+		envelope := &Envelope{
+			Kind:      EnvelopeResponse,
+			Token:     context.Token.Token,
+			Sequence:  context.Token.NextSend(),
+			Timestamp: time.Now().UnixMilli(),
+			Body:      responseData,
+		}
+		if context.Header != nil {
+			envelope.Method = context.Header.Method
+		}
+		responseSignature := rpc.CreateSignature(envelope, context)
+		resp.Header().Set("Message-Signature", responseSignature)
+		resp.Header().Set("Message-Sequence", strconv.FormatInt(int64(envelope.Sequence), 10))
+		resp.Header().Set("Message-Timestamp", strconv.FormatInt(envelope.Timestamp, 10))
+	}
+	// repackage request method header so client doesn't need to keep track of it
+	if context.Header != nil && context.Header.Method != "" {
+		resp.Header().Set("Request-Method", context.Header.Method)
+	}
+	encodedData := rpc.encodeBody(resp, context.ResponseTransport, responseData)
+	resp.WriteHeader(status)
+
+	// send response
+	_, err = resp.Write(encodedData)
+	if err != nil {
+		rpc.Logger.Warn("Error writing response - ", err)
+	}
+}
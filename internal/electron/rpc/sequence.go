@@ -0,0 +1,78 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+// SequenceWindowSize is how far behind the highest sequence received a
+// message may arrive & still be accepted
+const SequenceWindowSize = 64
+
+// SequenceWindow keeps track of the message sequences received from a peer.
+// Calls may overlap, such as a cancel sent while the call it cancels is in
+// flight, so messages don't always arrive in the order they were numbered.
+// Each sequence is accepted once, as long as it isn't too far behind the
+// highest one accepted so far.
+// A window isn't safe for concurrent use, so its owner must lock around it.
+type SequenceWindow struct {
+	// Highest is the highest sequence accepted
+	Highest int32
+	// bit n is set once sequence Highest-n has been accepted
+	seen uint64
+}
+
+// Reset restarts the window as if every sequence up to & including highest
+// had been accepted
+func (window *SequenceWindow) Reset(highest int32) {
+	window.Highest = highest
+	window.seen = 1
+}
+
+// Check reports whether a sequence would be accepted without recording it
+func (window *SequenceWindow) Check(sequence int32) bool {
+	if sequence <= 0 {
+		return false
+	}
+	if sequence > window.Highest {
+		return true
+	}
+	behind := int64(window.Highest) - int64(sequence)
+	if behind >= SequenceWindowSize {
+		return false
+	}
+	return window.seen&(1<<uint(behind)) == 0
+}
+
+// Accept records a sequence, reporting false if it can't be accepted
+func (window *SequenceWindow) Accept(sequence int32) bool {
+	if !window.Check(sequence) {
+		return false
+	}
+	if sequence > window.Highest {
+		ahead := int64(sequence) - int64(window.Highest)
+		if ahead >= SequenceWindowSize {
+			window.seen = 0
+		} else {
+			window.seen <<= uint(ahead)
+		}
+		window.Highest = sequence
+		window.seen |= 1
+		return true
+	}
+	window.seen |= 1 << uint(window.Highest-sequence)
+	return true
+}
@@ -19,16 +19,23 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package rpc
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/hex"
-	"io"
+	"errors"
 	"log"
-	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	"github.com/farrcraft/brewtheory/internal/electron/jobs"
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
 )
 
 // Handler is an RPC message handler
@@ -39,23 +46,117 @@ type RequestHeader struct {
 	Signature []byte
 	Method    string
 	Sequence  int32
+	Timestamp int64
 	Token     string
+	// Timeout is the optional deadline the client gave the call
+	Timeout time.Duration
 }
 
 // RequestContext provides contextual information about a request
 type RequestContext struct {
+	Server *Server
 	Token  *ClientToken
 	Header *RequestHeader
+	// APIToken is set instead of Token for calls made through the JSON
+	// gateway
+	APIToken *APIToken
+	// Context is done when the client disconnects, the call's timeout
+	// passes or the call is cancelled
+	Context context.Context
+	// how the request body was sent & how the response will be sent
+	RequestTransport  Transport
+	ResponseTransport Transport
+
+	// calls dispatched within another request share its rollbacks & audit
+	// entries
+	parent    *RequestContext
+	verified  bool
+	undoable  bool
+	rollbacks []func()
+	changes   []AuditChange
+	audits    []AuditEntry
+}
+
+// root returns the context of the top level request
+func (context *RequestContext) root() *RequestContext {
+	for context.parent != nil {
+		context = context.parent
+	}
+	return context
+}
+
+// OnRollback registers a function that undoes a handler's changes.
+// It is only called when the handler ran as part of an atomic batch that
+// failed.
+func (context *RequestContext) OnRollback(undo func()) {
+	context.undoable = true
+	root := context.root()
+	root.rollbacks = append(root.rollbacks, undo)
+}
+
+// Rollback runs the registered rollbacks, newest first.
+// Only the audit entries of calls that registered a rollback are marked as
+// rolled back.
+func (context *RequestContext) Rollback() {
+	if context.parent != nil {
+		context.parent.Rollback()
+		return
+	}
+	if len(context.rollbacks) == 0 {
+		return
+	}
+	for i := len(context.rollbacks) - 1; i >= 0; i-- {
+		context.rollbacks[i]()
+	}
+	context.rollbacks = nil
+	for i := range context.audits {
+		if context.audits[i].undoable {
+			context.audits[i].RolledBack = true
+		}
+	}
 }
 
 // Server is a RPC server instance
 type Server struct {
 	Logger      *logrus.Logger
 	Certificate tls.Certificate
+	Identity    *Identity
 	Status      chan string
 	Shutdown    chan bool
 	Handlers    map[string]Handler
-	Clients     map[string]*ClientToken
+	Methods     map[string]*Method
+	Sessions    *Sessions
+	Events      *EventBus
+	Pairing     *Pairing
+	Jobs        *jobs.Manager
+	Middleware  []Middleware
+	// AppVersion & Capabilities are offered to clients during key exchange
+	AppVersion   string
+	Capabilities []string
+	// MaxClockSkew bounds how far a request timestamp may be from now
+	MaxClockSkew time.Duration
+	// DisableTLS serves plain HTTP, which is only allowed on unix sockets
+	DisableTLS bool
+	// Limits bound request sizes, timeouts & rates
+	Limits     Limits
+	Rejections *Rejections
+	Metrics    *Metrics
+	// APITokens enables the JSON gateway when set
+	APITokens *APITokens
+	// Audit records calls to mutating methods when set
+	Audit *AuditJournal
+	// Logs lets clients change log levels at runtime when set
+	Logs *logging.Loggers
+
+	replays *replayCache
+	limiter *rateLimiter
+
+	// the running HTTP server is guarded so Stop can race with Start
+	mutex      sync.Mutex
+	httpServer *http.Server
+	stopped    bool
+	// done is closed once the server is stopped
+	done chan struct{}
 }
 
 // NewServer creates a new RPCServer instance
@@ -63,26 +164,44 @@ func NewServer(logger *logrus.Logger, Status chan string, Shutdown chan bool) *S
 	server := &Server{
 		Logger:   logger,
 		Handlers: make(map[string]Handler, 0),
+		Methods:  make(map[string]*Method, 0),
 		Status:   Status,
 		Shutdown: Shutdown,
-		Clients:  make(map[string]*ClientToken),
+		Sessions: NewSessions(logger),
+		Events:   NewEventBus(logger),
+		Pairing:  NewPairing(logger, nil, false),
+
+		Capabilities: DefaultCapabilities,
+
+		MaxClockSkew: DefaultMaxClockSkew,
+		Limits:       DefaultLimits,
+		Rejections:   &Rejections{},
+		Metrics:      NewMetrics(),
+		replays:      newReplayCache(),
+		limiter:      newRateLimiter(),
+		done:         make(chan struct{}),
+	}
+	// a client's event streams & calls in flight end with its session
+	server.Sessions.OnEnd = func(client *ClientToken) {
+		server.Events.CloseToken(client)
+		client.cancelCalls()
 	}
 	return server
 }
 
 // VerifyHeaders checks that a request contains the correct headers &
 // extracts their values into a working structure
-func (rpc *Server) VerifyHeaders(req *http.Request, context *RequestContext) bool {
+func (rpc *Server) VerifyHeaders(req *http.Request, context *RequestContext) error {
 	context.Header = &RequestHeader{}
 
 	context.Header.Method = req.Header.Get("Request-Method")
 	if context.Header.Method == "" {
 		rpc.Logger.Warn("Missing request method")
-		return false
+		return codes.New(codes.ScopeRPC, codes.ErrorBadRequest)
 	}
 
 	if context.Header.Method == "SERVICE-READY" {
-		return true
+		return nil
 	}
 
 	// Token creation is part of key exchange, so it doesn't exist here yet
@@ -90,78 +209,144 @@ func (rpc *Server) VerifyHeaders(req *http.Request, context *RequestContext) boo
 		context.Header.Token = req.Header.Get("Client-Token")
 		if context.Header.Token == "" {
 			rpc.Logger.Warn("Missing request client token")
-			return false
+			return codes.New(codes.ScopeRPC, codes.ErrorUnauthenticated)
 		}
 		var ok bool
-		context.Token, ok = rpc.Clients[context.Header.Token]
+		context.Token, ok = rpc.Sessions.Get(context.Header.Token)
 		if !ok {
 			rpc.Logger.Warn("Invalid client token")
-			return false
+			return codes.New(codes.ScopeRPC, codes.ErrorUnauthenticated)
 		}
 	}
 
-	// base64 encoded signature of the request body
+	// hex encoded signature of the request body
 	signature := req.Header.Get("Message-Signature")
 	if signature == "" {
 		rpc.Logger.Warn("Missing request signature")
-		return false
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSignature)
 	}
 	var err error
 	context.Header.Signature, err = hex.DecodeString(signature)
 	if err != nil {
 		rpc.Logger.Warn("Error decoding request signature - ", err)
-		return false
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSignature)
 	}
 
 	seq := req.Header.Get("Message-Sequence")
 	if seq == "" {
 		rpc.Logger.Warn("Missing request sequence")
-		return false
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSequence)
 	}
 	parsedSeq, err := strconv.ParseInt(seq, 10, 32)
 	if err != nil {
 		rpc.Logger.Warn("Error decoding request sequence - ", err)
-		return false
+		return codes.New(codes.ScopeRPC, codes.ErrorBadSequence)
 	}
 	context.Header.Sequence = int32(parsedSeq)
 
+	ts := req.Header.Get("Message-Timestamp")
+	if ts == "" {
+		rpc.Logger.Warn("Missing request timestamp")
+		return codes.New(codes.ScopeRPC, codes.ErrorBadTimestamp)
+	}
+	context.Header.Timestamp, err = strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		rpc.Logger.Warn("Error decoding request timestamp - ", err)
+		return codes.New(codes.ScopeRPC, codes.ErrorBadTimestamp)
+	}
+	skew := time.Since(time.UnixMilli(context.Header.Timestamp))
+	if skew > rpc.MaxClockSkew || skew < -rpc.MaxClockSkew {
+		rpc.Logger.Warn("Request timestamp outside of allowed clock skew - ", skew)
+		return codes.New(codes.ScopeRPC, codes.ErrorBadTimestamp)
+	}
+
+	context.Header.Timeout, err = rpc.requestTimeout(req)
+	if err != nil {
+		return err
+	}
+
+	// the sequence is only accepted once the signature has been verified, so
+	// requests that can't be verified don't move the session's counters
 	if context.Header.Method != "KeyExchange" {
-		context.Token.RecvCounter++
-		if context.Header.Sequence != context.Token.RecvCounter {
-			rpc.Logger.Warn("Invalid message sequence received. Expected [", context.Token.RecvCounter, "] but got [", context.Header.Sequence, "]")
-			return false
+		highest, ok := context.Token.CheckRecv(context.Header.Sequence)
+		if !ok {
+			rpc.Logger.Warn("Invalid message sequence received. Got [", context.Header.Sequence, "] after [", highest, "]")
+			return codes.New(codes.ScopeRPC, codes.ErrorBadSequence)
 		}
 	}
 
-	return true
+	return nil
+}
+
+// requestTimeout parses the optional Request-Timeout header, given in
+// milliseconds
+func (rpc *Server) requestTimeout(req *http.Request) (time.Duration, error) {
+	timeout := req.Header.Get("Request-Timeout")
+	if timeout == "" {
+		return 0, nil
+	}
+	ms, err := strconv.ParseInt(timeout, 10, 64)
+	if err != nil || ms <= 0 {
+		rpc.Logger.Warn("Invalid request timeout [", timeout, "]")
+		return 0, codes.New(codes.ScopeRPC, codes.ErrorBadRequest)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
 }
 
 // ServeHTTP handles HTTP requests
 func (rpc *Server) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	rpc.Logger.Debug("PING")
 
+	context := &RequestContext{
+		Server:            rpc,
+		ResponseTransport: responseTransport(req),
+	}
+
+	// the json gateway is only served once it has been enabled
+	if rpc.APITokens != nil && strings.HasPrefix(req.URL.Path, GatewayPrefix) {
+		rpc.serveJSON(resp, req, context)
+		return
+	}
+
 	// we only accept POST requests
 	if req.Method != "POST" {
 		rpc.Logger.Warn("Unexpected request method - ", req.Method)
+		resp.Header().Set("Allow", "POST")
+		rpc.reject(resp, context, codes.New(codes.ScopeRPC, codes.ErrorUnsupportedVerb))
 		return
 	}
 
-	// we accept only one URL path of "/rpc"
-	if req.URL.Path != "/rpc" {
+	// we accept "/rpc" for request/response calls & "/events" for event streams
+	if req.URL.Path != "/rpc" && req.URL.Path != "/events" {
 		rpc.Logger.Warn("Unexpected request path - ", req.URL.Path)
+		rpc.reject(resp, context, codes.New(codes.ScopeRPC, codes.ErrorUnknownPath))
 		return
 	}
 
-	context := &RequestContext{}
+	if !rpc.admitPreAuth(resp, req, context) {
+		return
+	}
 
-	if !rpc.VerifyHeaders(req, context) {
+	err := rpc.VerifyHeaders(req, context)
+	if err != nil {
 		rpc.Logger.Warn("Failed verifying request headers")
+		rpc.reject(resp, context, err)
+		return
+	}
+
+	if !rpc.admitToken(resp, context) || !rpc.admitBody(resp, req, context) {
 		return
 	}
 
-	body, err := io.ReadAll(req.Body)
+	context.RequestTransport, err = requestTransport(req)
 	if err != nil {
-		rpc.Logger.Warn("Error reading request body - ", err)
+		rpc.Logger.Warn("Unsupported request content type [", req.Header.Get("Content-Type"), "] or encoding [", req.Header.Get("Content-Encoding"), "]")
+		rpc.reject(resp, context, err)
+		return
+	}
+
+	if req.URL.Path == "/events" {
+		rpc.serveSubscribe(resp, req, context)
 		return
 	}
 
@@ -178,59 +363,71 @@ func (rpc *Server) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	handler := rpc.FindHandler(context.Header.Method)
 	if handler == nil {
 		rpc.Logger.Warn("Could not find handler for method - ", context.Header.Method)
+		rpc.reject(resp, context, codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod))
 		return
 	}
 
-	decodedBody, err := hex.DecodeString(string(body))
+	decodedBody, err := rpc.ReadBody(req, context)
 	if err != nil {
-		rpc.Logger.Warn("Error decoding request body - ", err)
+		rpc.reject(resp, context, err)
 		return
 	}
 
-	// key exchange requests contain the key needed to do verification
-	// so we need to defer until after the request has been handled
-	if context.Header.Method != "KeyExchange" {
-		ok := rpc.VerifyRequest(decodedBody, context.Header.Signature, context)
-		if !ok {
-			rpc.Logger.Warn("Message Verification failed")
+	// key exchange requests carry the key needed to verify them, but they are
+	// still verified before the handler can pair or create a session
+	if context.Header.Method == "KeyExchange" {
+		if !rpc.admitKeyExchange(resp, req, decodedBody, context) {
 			return
 		}
+		err = rpc.verifyKeyExchange(decodedBody, context)
+	} else {
+		err = rpc.authenticate(decodedBody, context)
 	}
-
-	handlerResponse, err := handler(rpc, decodedBody, context)
 	if err != nil {
+		rpc.reject(resp, context, err)
 		return
 	}
 
-	if context.Header.Method == "KeyExchange" {
-		ok := rpc.VerifyRequest(decodedBody, context.Header.Signature, context)
-		if !ok {
-			rpc.Logger.Warn("Message Verification failed")
-			return
-		}
-	}
+	var cancel func()
+	context.Context, cancel = rpc.callContext(req, context)
+	defer cancel()
 
-	responseData, err := proto.Marshal(handlerResponse)
+	handlerResponse, err := rpc.chain(handler)(rpc, decodedBody, context)
+	rpc.writeAudit(context)
+	if err != nil && context.Err() != nil {
+		// report why the call stopped rather than how the handler noticed
+		err = context.Err()
+	}
 	if err != nil {
-		rpc.Logger.Warn("Error marshaling response - ", err)
+		rpc.Logger.Error("Handler for method [", context.Header.Method, "] failed - ", err)
+		rpc.WriteError(resp, context, err)
 		return
 	}
-	encodedData := hex.EncodeToString(responseData)
 
-	// set response headers
-	responseSignature := rpc.CreateSignature(responseData, context)
-	resp.Header().Set("Message-Signature", responseSignature)
+	rpc.WriteMessage(resp, context, http.StatusOK, handlerResponse)
+}
 
-	context.Token.SendCounter++
-	resp.Header().Set("Message-Sequence", strconv.FormatInt(int64(context.Token.SendCounter), 10))
-	// repackage request method header so client doesn't need to keep track of it
-	resp.Header().Set("Request-Method", context.Header.Method)
+// serveSubscribe verifies a subscribe request before handing the connection
+// over to the event stream
+func (rpc *Server) serveSubscribe(resp http.ResponseWriter, req *http.Request, context *RequestContext) {
+	if context.Header.Method != "Subscribe" {
+		rpc.Logger.Warn("Unexpected event stream method - ", context.Header.Method)
+		rpc.reject(resp, context, codes.New(codes.ScopeRPC, codes.ErrorUnknownMethod))
+		return
+	}
 
-	// send response
-	_, err = resp.Write([]byte(encodedData))
+	decodedBody, err := rpc.ReadBody(req, context)
+	if err != nil {
+		rpc.reject(resp, context, err)
+		return
+	}
+	err = rpc.authenticate(decodedBody, context)
 	if err != nil {
-		rpc.Logger.Warn("Error writing response - ", err)
+		rpc.reject(resp, context, err)
+		return
 	}
+
+	rpc.ServeEvents(resp, req, decodedBody, context)
 }
 
 // FindHandler matches a method name with a handler
@@ -243,40 +440,131 @@ func (rpc *Server) FindHandler(requestMethod string) Handler {
 	return nil
 }
 
-// Start an RPC Server
-func (rpc *Server) Start(port string) bool {
-	ok := rpc.createCertificate()
-	if !ok {
-		rpc.Shutdown <- false
+// Start an RPC Server listening on a TCP address or a unix socket
+// (see ParseUnixAddress).
+// Start blocks until the server has been stopped.
+func (rpc *Server) Start(address string) bool {
+	if rpc.DisableTLS && !IsUnixAddress(address) {
+		rpc.Logger.Error("TLS can only be disabled when listening on a unix socket")
+		rpc.fail(codes.New(codes.ScopeRPC, codes.ErrorTLSRequired))
 		return false
 	}
 
-	conn, err := net.Listen("tcp", port)
+	var ok bool
+	rpc.Identity, ok = NewIdentity(rpc.Logger)
+	if !ok || !rpc.Identity.Load() {
+		rpc.fail(codes.New(codes.ScopeRPC, codes.ErrorIdentity))
+		return false
+	}
+	rpc.Certificate = rpc.Identity.Certificate
+
+	listener, err := Listen(rpc.Logger, address)
 	if err != nil {
 		rpc.Logger.Warn("Listen error - ", err)
-		rpc.Shutdown <- false
+		rpc.fail(codes.New(codes.ScopeRPC, codes.ErrorListen))
 		return false
 	}
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{rpc.Certificate},
+	if !rpc.DisableTLS {
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{rpc.Certificate},
+		}
+		listener = tls.NewListener(listener, tlsConfig)
 	}
-	tlsListener := tls.NewListener(conn, tlsConfig)
 	writer := rpc.Logger.Writer()
 	defer writer.Close()
+	// there is no write timeout since event streams & long running calls
+	// legitimately keep writing for a long time
 	server := &http.Server{
-		Addr:     port,
-		Handler:  rpc,
-		ErrorLog: log.New(writer, "", 0),
+		Handler:           rpc,
+		ErrorLog:          log.New(writer, "", 0),
+		ReadHeaderTimeout: rpc.Limits.ReadHeaderTimeout,
+		ReadTimeout:       rpc.Limits.ReadTimeout,
+		IdleTimeout:       rpc.Limits.IdleTimeout,
 	}
-	rpc.Logger.Debug("RPC listening on port [", port, "]")
 
-	// send a token to stdout so the frontend knows the backend is done initializing
-	rpc.Status <- "SERVICE_READY"
+	rpc.mutex.Lock()
+	if rpc.stopped {
+		rpc.mutex.Unlock()
+		listener.Close()
+		return false
+	}
+	rpc.httpServer = server
+	rpc.mutex.Unlock()
 
-	server.Serve(tlsListener)
+	rpc.Logger.Debug("RPC listening on [", listener.Addr(), "]")
+
+	done := make(chan struct{})
+	defer close(done)
+	go rpc.expireSessions(done)
+
+	// tell the frontend where we're listening & which certificate to pin
+	rpc.sendStatus(rpc.announceReady(listener, address))
+
+	err = server.Serve(listener)
+	if !errors.Is(err, http.ErrServerClosed) {
+		rpc.Logger.Error("RPC server error - ", err)
+		rpc.RequestShutdown(false)
+		return false
+	}
+	return true
+}
+
+// expireSessions periodically ends expired sessions until done is closed
+func (rpc *Server) expireSessions(done chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rpc.Sessions.Expire()
+		case <-done:
+			return
+		}
+	}
+}
+
+// Stop gracefully shuts down the server.
+// New connections are refused immediately while in-flight requests are
+// allowed to complete until the context deadline expires, at which point any
+// remaining connections are forcibly closed.
+func (rpc *Server) Stop(ctx context.Context) bool {
+	rpc.mutex.Lock()
+	if !rpc.stopped {
+		rpc.stopped = true
+		close(rpc.done)
+	}
+	server := rpc.httpServer
+	rpc.mutex.Unlock()
+
+	if server == nil {
+		return true
+	}
+
+	// event streams never go idle on their own so they have to be ended
+	// before the server can finish draining
+	server.RegisterOnShutdown(rpc.Events.Close)
+	err := server.Shutdown(ctx)
+	if err != nil {
+		rpc.Logger.Warn("Error draining RPC requests - ", err)
+		err = server.Close()
+		if err != nil {
+			rpc.Logger.Error("Error closing RPC server - ", err)
+		}
+		return false
+	}
+	rpc.Logger.Debug("RPC server stopped")
 	return true
 }
 
-// Stop performs shutdown routines before application termination
-func (rpc *Server) Stop() {
+// RequestShutdown asks the owning service to begin an orderly shutdown.
+// It never blocks so it is safe to call from inside a handler whose own
+// response must be written before the server can drain.  The Shutdown
+// channel is buffered, so a request is only dropped when one is already
+// pending & the service is on its way down regardless.
+func (rpc *Server) RequestShutdown(ok bool) {
+	select {
+	case rpc.Shutdown <- ok:
+	default:
+		rpc.Logger.Debug("Shutdown already requested")
+	}
 }
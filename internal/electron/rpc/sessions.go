@@ -0,0 +1,166 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// Default session limits
+const (
+	DefaultIdleTimeout = 8 * time.Hour
+	DefaultMaxLifetime = 24 * time.Hour
+	DefaultMaxSessions = 16
+)
+
+// Sessions owns the client tokens of every paired client.
+// It is safe for concurrent use by request goroutines.
+type Sessions struct {
+	Logger *logrus.Logger
+	// IdleTimeout ends a session that hasn't sent a request for this long
+	IdleTimeout time.Duration
+	// MaxLifetime ends a session this long after its key exchange
+	MaxLifetime time.Duration
+	// MaxSessions caps the number of concurrent sessions
+	MaxSessions int
+	// OnEnd is called whenever a session ends for any reason
+	OnEnd func(*ClientToken)
+
+	mutex  sync.Mutex
+	tokens map[string]*ClientToken
+}
+
+// NewSessions creates a new session manager with the default limits
+func NewSessions(logger *logrus.Logger) *Sessions {
+	sessions := &Sessions{
+		Logger:      logger,
+		IdleTimeout: DefaultIdleTimeout,
+		MaxLifetime: DefaultMaxLifetime,
+		MaxSessions: DefaultMaxSessions,
+		tokens:      make(map[string]*ClientToken),
+	}
+	return sessions
+}
+
+// Add starts a new session for a token
+func (sessions *Sessions) Add(token *ClientToken) error {
+	sessions.mutex.Lock()
+	ended := sessions.expire(time.Now())
+	full := sessions.MaxSessions > 0 && len(sessions.tokens) >= sessions.MaxSessions
+	if !full {
+		sessions.tokens[token.Token] = token
+	}
+	sessions.mutex.Unlock()
+
+	sessions.notify(ended)
+	if full {
+		sessions.Logger.Warn("Session limit of [", sessions.MaxSessions, "] reached")
+		return codes.New(codes.ScopeRPC, codes.ErrorTooManySessions)
+	}
+	return nil
+}
+
+// Get finds the session for a token.
+// An expired session is ended & not returned.
+func (sessions *Sessions) Get(token string) (*ClientToken, bool) {
+	sessions.mutex.Lock()
+	client, ok := sessions.tokens[token]
+	if ok && client.expired(time.Now(), sessions.IdleTimeout, sessions.MaxLifetime) {
+		delete(sessions.tokens, token)
+		sessions.mutex.Unlock()
+		sessions.Logger.Debug("Session expired")
+		sessions.notify([]*ClientToken{client})
+		return nil, false
+	}
+	sessions.mutex.Unlock()
+	return client, ok
+}
+
+// End ends a single session
+func (sessions *Sessions) End(token string) bool {
+	sessions.mutex.Lock()
+	client, ok := sessions.tokens[token]
+	delete(sessions.tokens, token)
+	sessions.mutex.Unlock()
+
+	if ok {
+		sessions.notify([]*ClientToken{client})
+	}
+	return ok
+}
+
+// RevokeAll ends every session & returns how many were ended
+func (sessions *Sessions) RevokeAll() int {
+	sessions.mutex.Lock()
+	ended := make([]*ClientToken, 0, len(sessions.tokens))
+	for _, client := range sessions.tokens {
+		ended = append(ended, client)
+	}
+	sessions.tokens = make(map[string]*ClientToken)
+	sessions.mutex.Unlock()
+
+	sessions.notify(ended)
+	return len(ended)
+}
+
+// Expire ends every session that has been idle or alive for too long
+func (sessions *Sessions) Expire() {
+	sessions.mutex.Lock()
+	ended := sessions.expire(time.Now())
+	sessions.mutex.Unlock()
+
+	if len(ended) > 0 {
+		sessions.Logger.Debug("Expired [", len(ended), "] sessions")
+	}
+	sessions.notify(ended)
+}
+
+// Count returns the number of active sessions
+func (sessions *Sessions) Count() int {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+	return len(sessions.tokens)
+}
+
+// expire must be called with the mutex held
+func (sessions *Sessions) expire(now time.Time) []*ClientToken {
+	var ended []*ClientToken
+	for token, client := range sessions.tokens {
+		if client.expired(now, sessions.IdleTimeout, sessions.MaxLifetime) {
+			delete(sessions.tokens, token)
+			ended = append(ended, client)
+		}
+	}
+	return ended
+}
+
+// notify must be called without the mutex held
+func (sessions *Sessions) notify(ended []*ClientToken) {
+	if sessions.OnEnd == nil {
+		return
+	}
+	for _, client := range ended {
+		sessions.OnEnd(client)
+	}
+}
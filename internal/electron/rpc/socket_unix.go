@@ -0,0 +1,36 @@
+//go:build unix
+
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates a socket file that only the owning user can connect to.
+// The umask is narrowed while the socket is created, so there is no window in
+// which it has looser permissions.  The umask applies to the whole process,
+// so this should only happen while the service is starting up.
+func listenUnix(path string) (net.Listener, error) {
+	mask := syscall.Umask(0777 &^ UnixSocketMode)
+	defer syscall.Umask(mask)
+	return net.Listen("unix", path)
+}
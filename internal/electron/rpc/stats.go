@@ -0,0 +1,89 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+)
+
+// Rejections counts the requests turned away before reaching a handler,
+// keyed by the reason they were refused
+type Rejections struct {
+	mutex  sync.Mutex
+	counts map[string]uint64
+}
+
+// Add counts a rejected request
+func (rejections *Rejections) Add(reason string) {
+	rejections.mutex.Lock()
+	defer rejections.mutex.Unlock()
+	if rejections.counts == nil {
+		rejections.counts = make(map[string]uint64)
+	}
+	rejections.counts[reason]++
+}
+
+// Counts returns a copy of the current counters
+func (rejections *Rejections) Counts() map[string]uint64 {
+	rejections.mutex.Lock()
+	defer rejections.mutex.Unlock()
+	counts := make(map[string]uint64, len(rejections.counts))
+	for reason, count := range rejections.counts {
+		counts[reason] = count
+	}
+	return counts
+}
+
+// rejectReason names the counter a rejection is recorded under
+func rejectReason(code codes.Code) string {
+	switch code {
+	case codes.ErrorBadRequest, codes.ErrorDecode:
+		return "bad_request"
+	case codes.ErrorUnsupportedVerb:
+		return "unsupported_verb"
+	case codes.ErrorUnknownPath:
+		return "unknown_path"
+	case codes.ErrorUnknownMethod:
+		return "unknown_method"
+	case codes.ErrorUnauthenticated:
+		return "unauthenticated"
+	case codes.ErrorBadSignature:
+		return "bad_signature"
+	case codes.ErrorBadSequence:
+		return "bad_sequence"
+	case codes.ErrorBadTimestamp:
+		return "bad_timestamp"
+	case codes.ErrorUnsupportedEncoding:
+		return "unsupported_encoding"
+	case codes.ErrorBodyTooLarge:
+		return "body_too_large"
+	case codes.ErrorRateLimited:
+		return "rate_limited"
+	}
+	return "other"
+}
+
+// reject counts & reports a request refused before reaching its handler
+func (rpc *Server) reject(resp http.ResponseWriter, context *RequestContext, err error) {
+	rpc.Rejections.Add(rejectReason(codes.ToInternalError(err).Code))
+	rpc.WriteError(resp, context, err)
+}
@@ -19,29 +19,49 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package rpc
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 // ClientToken identifies a client that can communicate with the server
 // Each client has its own set of counters and signing keys
+// Once a token has been added to the session manager it is shared between
+// request goroutines, so the counters & keys must only be accessed through
+// its methods.
 type ClientToken struct {
 	Token           string
-	RecvCounter     int32
+	RecvWindow      SequenceWindow
 	SendCounter     int32
 	SignPublicKey   ed25519.PublicKey
 	SignPrivateKey  ed25519.PrivateKey // Key used for signing responses
 	VerifyPublicKey ed25519.PublicKey  // Key used for verifying requests
+	CreatedAt       time.Time
+	LastSeen        time.Time
+	// negotiated during key exchange & fixed for the life of the session
+	ProtocolVersion int32
+	AppVersion      string
+	Capabilities    map[string]bool
+
+	mutex sync.Mutex
+	// calls in flight by request sequence, so they can be cancelled
+	calls map[int32]context.CancelFunc
 }
 
 // NewClientToken creates a new ClientToken
 func NewClientToken(logger *logrus.Logger) (*ClientToken, error) {
+	now := time.Now()
 	client := &ClientToken{
-		RecvCounter: 0,
 		SendCounter: 0,
+		CreatedAt:   now,
+		LastSeen:    now,
 	}
 
 	// The identifier token is just a url encoded random string
@@ -62,3 +82,144 @@ func NewClientToken(logger *logrus.Logger) (*ClientToken, error) {
 
 	return client, nil
 }
+
+// ID identifies the session in logs & the audit journal without revealing
+// the token itself
+func (client *ClientToken) ID() string {
+	digest := sha256.Sum256([]byte(client.Token))
+	return hex.EncodeToString(digest[:8])
+}
+
+// Rekey replaces the signing keys & the client's verification key.
+// Both sequences restart as if the rekey request was the first message of a
+// new key exchange.
+func (client *ClientToken) Rekey(verifyKey ed25519.PublicKey, logger *logrus.Logger) error {
+	signPublicKey, signPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		logger.Warn("Error generating signing keys - ", err)
+		return err
+	}
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.SignPublicKey = signPublicKey
+	client.SignPrivateKey = signPrivateKey
+	client.VerifyPublicKey = make([]byte, len(verifyKey))
+	copy(client.VerifyPublicKey, verifyKey)
+	client.SendCounter = 0
+	client.RecvWindow.Reset(1)
+	return nil
+}
+
+// PublicKey returns the key clients use to verify server messages
+func (client *ClientToken) PublicKey() ed25519.PublicKey {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.SignPublicKey
+}
+
+// Sign signs a message with the token's private key
+func (client *ClientToken) Sign(message []byte) []byte {
+	client.mutex.Lock()
+	key := client.SignPrivateKey
+	client.mutex.Unlock()
+	return ed25519.Sign(key, message)
+}
+
+// Verify checks a message signature against the client's public key
+func (client *ClientToken) Verify(message []byte, sig []byte) bool {
+	client.mutex.Lock()
+	key := client.VerifyPublicKey
+	client.mutex.Unlock()
+	if len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(key, message, sig)
+}
+
+// CheckRecv reports whether a request sequence could be accepted without
+// recording it.  The highest sequence accepted so far is returned for
+// logging.
+func (client *ClientToken) CheckRecv(sequence int32) (int32, bool) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.RecvWindow.Highest, client.RecvWindow.Check(sequence)
+}
+
+// AcceptRecv records the sequence of a request whose signature has been
+// verified.  It fails if the sequence was accepted in the meantime.
+func (client *ClientToken) AcceptRecv(sequence int32) bool {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if !client.RecvWindow.Accept(sequence) {
+		return false
+	}
+	client.LastSeen = time.Now()
+	return true
+}
+
+// NextSend advances & returns the send counter
+func (client *ClientToken) NextSend() int32 {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.SendCounter++
+	return client.SendCounter
+}
+
+// Track records a call in flight so it can be cancelled by its sequence
+func (client *ClientToken) Track(sequence int32, cancel context.CancelFunc) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if client.calls == nil {
+		client.calls = make(map[int32]context.CancelFunc)
+	}
+	client.calls[sequence] = cancel
+}
+
+// Untrack forgets a call once it has finished
+func (client *ClientToken) Untrack(sequence int32) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	delete(client.calls, sequence)
+}
+
+// CancelCall cancels the call in flight with the given sequence.
+// It reports whether there was such a call.
+func (client *ClientToken) CancelCall(sequence int32) bool {
+	client.mutex.Lock()
+	cancel, ok := client.calls[sequence]
+	client.mutex.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// cancelCalls cancels every call in flight
+func (client *ClientToken) cancelCalls() {
+	client.mutex.Lock()
+	calls := client.calls
+	client.calls = nil
+	client.mutex.Unlock()
+	for _, cancel := range calls {
+		cancel()
+	}
+}
+
+// HasCapability reports whether a capability was negotiated for the session
+func (client *ClientToken) HasCapability(capability string) bool {
+	return client.Capabilities[capability]
+}
+
+// expired reports whether the token has been idle or alive for too long
+func (client *ClientToken) expired(now time.Time, idle time.Duration, lifetime time.Duration) bool {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if idle > 0 && now.Sub(client.LastSeen) > idle {
+		return true
+	}
+	if lifetime > 0 && now.Sub(client.CreatedAt) > lifetime {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content types a message body can be sent as
+const (
+	// ContentTypeHex is a hex encoded protobuf message, the original format
+	ContentTypeHex = "application/octet-stream"
+	// ContentTypeProtobuf is a raw protobuf message
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Content encodings a message body can be compressed with
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// compressThreshold is the smallest response worth compressing
+const compressThreshold = 1024
+
+// zstdMaxWindow bounds how much memory a compressed request can make the
+// decoder allocate
+const zstdMaxWindow = 8 << 20
+
+// zstdEncoder is shared by all responses, EncodeAll is safe for concurrent use
+var zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+
+// Transport is how a message body is represented on the wire.
+// Signatures always cover the decoded protobuf bytes, so the transport can
+// change without affecting verification.
+type Transport struct {
+	Binary   bool   // raw protobuf instead of hex
+	Encoding string // content encoding, empty when uncompressed
+}
+
+// requestTransport determines how a request body was sent from its
+// Content-Type & Content-Encoding headers.  Requests without a content type
+// are hex encoded.
+func requestTransport(req *http.Request) (Transport, error) {
+	var transport Transport
+	contentType := req.Header.Get("Content-Type")
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return transport, codes.New(codes.ScopeRPC, codes.ErrorUnsupportedEncoding)
+		}
+		switch mediaType {
+		case ContentTypeProtobuf:
+			transport.Binary = true
+		case ContentTypeHex:
+		default:
+			return transport, codes.New(codes.ScopeRPC, codes.ErrorUnsupportedEncoding)
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case EncodingGzip:
+		transport.Encoding = EncodingGzip
+	case EncodingZstd:
+		transport.Encoding = EncodingZstd
+	default:
+		return transport, codes.New(codes.ScopeRPC, codes.ErrorUnsupportedEncoding)
+	}
+	return transport, nil
+}
+
+// responseTransport picks how to send the response from the request's
+// Accept & Accept-Encoding headers.  Hex is used unless the client asks for
+// raw protobuf.  Zstd is preferred over gzip when the client accepts both.
+func responseTransport(req *http.Request) Transport {
+	transport := Transport{
+		Binary: accepts(req.Header.Values("Accept"), ContentTypeProtobuf),
+	}
+	if accepts(req.Header.Values("Accept-Encoding"), EncodingZstd) {
+		transport.Encoding = EncodingZstd
+	} else if accepts(req.Header.Values("Accept-Encoding"), EncodingGzip) {
+		transport.Encoding = EncodingGzip
+	}
+	return transport
+}
+
+// accepts checks whether a value is listed in a set of Accept style headers
+// without having been refused with a zero quality
+func accepts(headers []string, value string) bool {
+	for _, header := range headers {
+		for _, part := range strings.Split(header, ",") {
+			fields := strings.Split(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(fields[0]), value) {
+				continue
+			}
+			quality := 1.0
+			for _, param := range fields[1:] {
+				name, q, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if ok && name == "q" {
+					quality, _ = strconv.ParseFloat(q, 64)
+				}
+			}
+			if quality > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadBody reads & decodes a request body into protobuf bytes
+func (rpc *Server) ReadBody(req *http.Request, context *RequestContext) ([]byte, error) {
+	var reader io.Reader = req.Body
+	if context.RequestTransport.Encoding != "" {
+		decompressor, err := Decompressor(context.RequestTransport.Encoding, req.Body)
+		if err != nil {
+			rpc.Logger.Warn("Error opening compressed request body - ", err)
+			return nil, codes.New(codes.ScopeRPC, codes.ErrorDecode)
+		}
+		defer decompressor.Close()
+		reader = decompressor
+		if rpc.Limits.MaxBodySize > 0 {
+			reader = io.LimitReader(decompressor, rpc.Limits.MaxBodySize+1)
+		}
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			rpc.Logger.Warn("Request body is too large")
+			return nil, codes.New(codes.ScopeRPC, codes.ErrorBodyTooLarge)
+		}
+		rpc.Logger.Warn("Error reading request body - ", err)
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorBadRequest)
+	}
+	if rpc.Limits.MaxBodySize > 0 && int64(len(body)) > rpc.Limits.MaxBodySize {
+		rpc.Logger.Warn("Decompressed request body is too large")
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorBodyTooLarge)
+	}
+	if context.RequestTransport.Binary {
+		return body, nil
+	}
+
+	decoded, err := hex.DecodeString(string(body))
+	if err != nil {
+		rpc.Logger.Warn("Error decoding request body - ", err)
+		return nil, codes.New(codes.ScopeRPC, codes.ErrorDecode)
+	}
+	return decoded, nil
+}
+
+// encodeBody renders protobuf bytes for the response transport & sets the
+// matching content headers
+func (rpc *Server) encodeBody(resp http.ResponseWriter, transport Transport, data []byte) []byte {
+	resp.Header().Add("Vary", "Accept, Accept-Encoding")
+	if transport.Binary {
+		resp.Header().Set("Content-Type", ContentTypeProtobuf)
+	} else {
+		data = []byte(hex.EncodeToString(data))
+		resp.Header().Set("Content-Type", ContentTypeHex)
+	}
+	if transport.Encoding == "" || len(data) < compressThreshold {
+		return data
+	}
+
+	compressed, err := Compress(transport.Encoding, data)
+	if err != nil {
+		rpc.Logger.Warn("Error compressing response - ", err)
+		return data
+	}
+	resp.Header().Set("Content-Encoding", transport.Encoding)
+	return compressed
+}
+
+// Compress compresses a message body with a content encoding
+func Compress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		_, err := gz.Write(data)
+		if err == nil {
+			err = gz.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return compressed.Bytes(), nil
+	case EncodingZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+	return nil, codes.New(codes.ScopeRPC, codes.ErrorUnsupportedEncoding)
+}
+
+// Decompressor opens a reader that expands a body compressed with a content
+// encoding
+func Decompressor(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewReader(r)
+	case EncodingZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return nil, codes.New(codes.ScopeRPC, codes.ErrorUnsupportedEncoding)
+}
@@ -0,0 +1,112 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpc
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+)
+
+// Validator is implemented by request messages that can check their own
+// contents before they are handed to a handler
+type Validator interface {
+	Validate() error
+}
+
+// Message constrains a type parameter to a pointer to a generated message
+type Message[T any] interface {
+	*T
+	proto.Message
+}
+
+// Register adds a typed handler to the server.
+// See Typed for how the handler is adapted.  The request & response message
+// descriptors are recorded so the method can be described by the catalog.
+func Register[Req any, Resp any, PReq Message[Req], PResp Message[Resp]](server *Server, method string, handler func(*RequestContext, PReq) (PResp, error), options ...MethodOption) {
+	request := PReq(new(Req)).ProtoReflect().Descriptor()
+	response := PResp(new(Resp)).ProtoReflect().Descriptor()
+	server.registerMethod(newMethod(method, request, response, options), Typed(handler))
+}
+
+// Typed adapts a handler that works with decoded messages into a Handler.
+// The request is decoded & validated before the handler is called.  The
+// response header is created if the handler didn't set one, and any error the
+// handler returns is reported in the header of an otherwise empty response.
+func Typed[Req any, Resp any, PReq Message[Req], PResp Message[Resp]](handler func(*RequestContext, PReq) (PResp, error)) Handler {
+	return func(server *Server, message []byte, context *RequestContext) (proto.Message, error) {
+		request := PReq(new(Req))
+		err := proto.Unmarshal(message, request)
+		if err != nil {
+			server.Logger.Warn("Error unmarshaling message - ", err)
+			return typedError[Resp, PResp](codes.New(codes.ScopeRPC, codes.ErrorDecode)), nil
+		}
+
+		if validator, ok := any(request).(Validator); ok {
+			err = validator.Validate()
+			if err != nil {
+				server.Logger.Warn("Invalid request for method [", context.Header.Method, "] - ", err)
+				return typedError[Resp, PResp](err), nil
+			}
+		}
+
+		response, err := handler(context, request)
+		if err != nil {
+			if !codes.IsInternalError(err) {
+				server.Logger.Error("Handler for method [", context.Header.Method, "] returned a non-internal error - ", err)
+			}
+			return typedError[Resp, PResp](err), nil
+		}
+		if response == nil {
+			response = PResp(new(Resp))
+		}
+		responseHeader(response)
+		return response, nil
+	}
+}
+
+// typedError creates a new response message carrying an error
+func typedError[Resp any, PResp Message[Resp]](err error) PResp {
+	response := PResp(new(Resp))
+	header := responseHeader(response)
+	if header != nil {
+		SetInternalError(header, err)
+	}
+	return response
+}
+
+// responseHeader finds the ResponseHeader embedded in a response message,
+// creating it if it hasn't been set yet
+func responseHeader(response proto.Message) *messages.ResponseHeader {
+	message := response.ProtoReflect()
+	fd := message.Descriptor().Fields().ByName("header")
+	if fd == nil || fd.Kind() != protoreflect.MessageKind {
+		return nil
+	}
+	if !message.Has(fd) {
+		message.Set(fd, protoreflect.ValueOfMessage(NewResponseHeader().ProtoReflect()))
+	}
+	header, ok := message.Get(fd).Message().Interface().(*messages.ResponseHeader)
+	if !ok {
+		return nil
+	}
+	return header
+}
@@ -0,0 +1,78 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpcclient
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+)
+
+// BatchCall is a single call made as part of a batch.
+// Response is filled in & Err is set from the call's result.
+type BatchCall struct {
+	Method   string
+	Request  proto.Message
+	Response proto.Message
+	Err      error
+}
+
+// Batch makes several calls with a single signed request.
+// In atomic mode the batch stops at the first failed call & the error of that
+// call is returned.  Otherwise the returned error only reports a failure of
+// the batch as a whole & each call's own error is in its Err.
+func (client *Client) Batch(atomic bool, calls ...*BatchCall) error {
+	request := &messages.BatchRequest{
+		Header: &messages.RequestHeader{Method: "Batch"},
+		Atomic: atomic,
+		Calls:  make([]*messages.BatchCall, len(calls)),
+	}
+	for i, call := range calls {
+		body, err := proto.Marshal(call.Request)
+		if err != nil {
+			return fmt.Errorf("error marshaling request for method [%s] - %w", call.Method, err)
+		}
+		request.Calls[i] = &messages.BatchCall{Method: call.Method, Body: body}
+	}
+
+	// a failed atomic batch still carries the results of its calls
+	response := &messages.BatchResponse{}
+	batchErr := client.Call("Batch", request, response)
+	if len(response.Results) != len(calls) {
+		if batchErr != nil {
+			return batchErr
+		}
+		return fmt.Errorf("expected [%d] batch results but got [%d]", len(calls), len(response.Results))
+	}
+
+	for i, call := range calls {
+		result := response.Results[i]
+		call.Err = responseError(result)
+		if call.Err != nil || call.Response == nil {
+			continue
+		}
+		err := proto.Unmarshal(result.Body, call.Response)
+		if err != nil {
+			call.Err = fmt.Errorf("error decoding response for method [%s] - %w", call.Method, err)
+		}
+	}
+	return batchErr
+}
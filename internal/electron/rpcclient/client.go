@@ -0,0 +1,677 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// compressThreshold is the smallest request body worth compressing
+const compressThreshold = 1024
+
+// DefaultEndpoint is the address the backend listens on by default
+const DefaultEndpoint = "https://localhost:53017/rpc"
+
+// These are the errors a client can return for transport level failures.
+// Application level failures are returned as *codes.InternalError values
+// built from the response header.
+var (
+	ErrNotPaired        = errors.New("client has not completed a key exchange")
+	ErrEmptyResponse    = errors.New("empty response body")
+	ErrMissingSignature = errors.New("missing response signature")
+	ErrBadSignature     = errors.New("response signature could not be verified")
+	ErrMissingSequence  = errors.New("missing response sequence")
+	ErrBadSequence      = errors.New("unexpected response sequence")
+	ErrBadTimestamp     = errors.New("response timestamp outside of allowed clock skew")
+	ErrIncompatible     = errors.New("server protocol version is not supported")
+	ErrMethodMismatch   = errors.New("response method does not match request")
+)
+
+// headeredResponse is satisfied by every response message since they all
+// embed a ResponseHeader
+type headeredResponse interface {
+	GetHeader() *messages.ResponseHeader
+}
+
+// SessionInfo is what client & server agreed on during key exchange
+type SessionInfo struct {
+	ProtocolVersion int32
+	AppVersion      string
+	Capabilities    []string
+}
+
+// Client is a native Go client for the backend RPC server.
+// It mirrors the Electron main process client: it performs the key exchange,
+// signs every request, verifies every response & keeps track of the message
+// sequence counters in both directions.
+type Client struct {
+	Endpoint        string
+	HTTP            *http.Client
+	Token           string
+	SendCounter     int32
+	RecvWindow      rpc.SequenceWindow
+	SignPublicKey   ed25519.PublicKey
+	SignPrivateKey  ed25519.PrivateKey // Key used for signing requests
+	VerifyPublicKey ed25519.PublicKey  // Key used for verifying responses
+	MaxClockSkew    time.Duration
+	// BootstrapSecret is proven during key exchange when the service requires it
+	BootstrapSecret []byte
+	// AppVersion & Capabilities are offered to the server during key exchange
+	AppVersion   string
+	Capabilities []string
+	// Session is what the server agreed to during the last key exchange
+	Session SessionInfo
+	// Binary sends & asks for raw protobuf bodies instead of hex
+	Binary bool
+	// Compression is the content encoding for large request bodies & the
+	// one asked for on responses, empty sends requests uncompressed
+	Compression string
+
+	// guards the session state, calls only hold it while signing a request &
+	// verifying its response
+	mutex sync.Mutex
+}
+
+// NewClient creates a new client for the server listening at endpoint.
+// If httpClient is nil, http.DefaultClient is used.
+func NewClient(endpoint string, httpClient *http.Client) (*Client, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	client := &Client{
+		Endpoint:     endpoint,
+		HTTP:         httpClient,
+		MaxClockSkew: rpc.DefaultMaxClockSkew,
+		Capabilities: rpc.DefaultCapabilities,
+	}
+
+	var err error
+	client.SignPublicKey, client.SignPrivateKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating signing keys - %w", err)
+	}
+	return client, nil
+}
+
+// NewHTTPClient creates an HTTP client that trusts the PEM encoded certificate
+// written by the backend to its config directory
+func NewHTTPClient(certificate []byte) (*http.Client, error) {
+	tlsConfig, err := trustCertificate(certificate)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// trustCertificate creates a TLS config that trusts a PEM encoded certificate
+func trustCertificate(certificate []byte) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certificate) {
+		return nil, errors.New("no certificates found in PEM data")
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	return tlsConfig, nil
+}
+
+// NewUnixClient creates a client for a service listening on a unix socket
+// address such as `unix:///path/to/socket` or `unix:@name`.
+// A nil certificate talks plain HTTP to a service running with TLS disabled.
+func NewUnixClient(address string, certificate []byte) (*Client, error) {
+	path, ok := rpc.ParseUnixAddress(address)
+	if !ok {
+		return nil, fmt.Errorf("invalid unix socket address [%s]", address)
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}
+	// the host only matters for checking the certificate
+	endpoint := "http://localhost/rpc"
+	if certificate != nil {
+		tlsConfig, err := trustCertificate(certificate)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+		endpoint = "https://localhost/rpc"
+	}
+	return NewClient(endpoint, &http.Client{Transport: transport})
+}
+
+// Ready asks the server whether it is ready to service requests
+func (client *Client) Ready() (bool, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	req, err := http.NewRequest(http.MethodPost, client.Endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Request-Method", "SERVICE-READY")
+
+	resp, err := client.HTTP.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	return resp.StatusCode == http.StatusOK && string(body) == "OK", nil
+}
+
+// KeyExchange pairs the client with the server.
+// The server responds with its own public key & a client token that
+// identifies this client in all later requests.
+func (client *Client) KeyExchange() error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	request := &messages.KeyExchangeRequest{
+		Header:          &messages.RequestHeader{Method: "KeyExchange"},
+		PublicKey:       client.SignPublicKey,
+		ProtocolVersion: rpc.ProtocolVersion,
+		AppVersion:      client.AppVersion,
+		Capabilities:    client.Capabilities,
+	}
+	if len(client.BootstrapSecret) != 0 {
+		request.BootstrapProof = rpc.BootstrapProof(client.BootstrapSecret, client.SignPublicKey)
+	}
+	// a new exchange always restarts both sequences
+	client.Token = ""
+	client.SendCounter = 0
+	client.RecvWindow = rpc.SequenceWindow{}
+	client.VerifyPublicKey = nil
+
+	response := &messages.KeyExchangeResponse{}
+	body, header, err := client.send("KeyExchange", request, 0)
+	if err != nil {
+		return err
+	}
+	err = proto.Unmarshal(body, response)
+	if err != nil {
+		return fmt.Errorf("error decoding response - %w", err)
+	}
+	if err := responseError(response); err != nil {
+		return err
+	}
+
+	// the response is signed with the key it carries, so verification has to
+	// wait until we have decoded it
+	client.VerifyPublicKey = make([]byte, len(response.PublicKey))
+	copy(client.VerifyPublicKey, response.PublicKey)
+	client.Token = response.Token
+	err = client.verify("KeyExchange", body, header)
+	if err != nil {
+		client.Token = ""
+		client.VerifyPublicKey = nil
+		return err
+	}
+
+	// servers that predate negotiation don't send a version
+	version := response.ProtocolVersion
+	if version == 0 {
+		version = rpc.LegacyProtocolVersion
+	}
+	if version < rpc.MinProtocolVersion {
+		client.Token = ""
+		client.VerifyPublicKey = nil
+		return fmt.Errorf("%w - [%d]", ErrIncompatible, version)
+	}
+	client.Session = SessionInfo{
+		ProtocolVersion: version,
+		AppVersion:      response.AppVersion,
+		Capabilities:    response.Capabilities,
+	}
+	return nil
+}
+
+// HasCapability reports whether the server agreed to a capability
+func (client *Client) HasCapability(capability string) bool {
+	for _, agreed := range client.Session.Capabilities {
+		if agreed == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Rekey replaces the keys of an existing session without creating a new one.
+// The request is signed with the current key; the response carries the
+// server's new key & both sequences restart.
+func (client *Client) Rekey() error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.Token == "" || len(client.VerifyPublicKey) == 0 {
+		return ErrNotPaired
+	}
+
+	signPublicKey, signPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating signing keys - %w", err)
+	}
+	request := &messages.KeyExchangeRequest{
+		Header:    &messages.RequestHeader{Method: "Rekey"},
+		PublicKey: signPublicKey,
+	}
+
+	body, header, err := client.send("Rekey", request, 0)
+	if err != nil {
+		return err
+	}
+	response := &messages.KeyExchangeResponse{}
+	err = proto.Unmarshal(body, response)
+	if err != nil {
+		return fmt.Errorf("error decoding response - %w", err)
+	}
+
+	// a refused rekey is signed with the current keys, which stay in use
+	if err := responseError(response); err != nil {
+		verifyErr := client.verify("Rekey", body, header)
+		if verifyErr != nil {
+			return verifyErr
+		}
+		return err
+	}
+
+	// a successful one is signed with the server's new key as the first
+	// message of its new sequence
+	verifyPublicKey, recvWindow := client.VerifyPublicKey, client.RecvWindow
+	client.VerifyPublicKey = make([]byte, len(response.PublicKey))
+	copy(client.VerifyPublicKey, response.PublicKey)
+	client.RecvWindow = rpc.SequenceWindow{}
+	err = client.verify("Rekey", body, header)
+	if err != nil {
+		client.VerifyPublicKey, client.RecvWindow = verifyPublicKey, recvWindow
+		return err
+	}
+
+	// the rekey request counts as the first message of the new sequence
+	client.SignPublicKey = signPublicKey
+	client.SignPrivateKey = signPrivateKey
+	client.SendCounter = 1
+	return nil
+}
+
+// Call invokes an RPC method on the server.
+// The response message is populated from the verified response body.
+// A non-OK response header is returned as a *codes.InternalError.
+func (client *Client) Call(method string, request proto.Message, response proto.Message) error {
+	return client.CallContext(context.Background(), method, request, response)
+}
+
+// CallContext invokes an RPC method, sending the deadline of ctx as the
+// call's timeout so the server stops the call once it passes.  When ctx is
+// cancelled the client asks the server to cancel the call & returns the
+// error the server responds with.
+// The client is only locked while the request is signed & its response is
+// verified, so calls may overlap.
+func (client *Client) CallContext(ctx context.Context, method string, request proto.Message, response proto.Message) error {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if timeout <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	client.mutex.Lock()
+	if client.Token == "" || len(client.VerifyPublicKey) == 0 {
+		client.mutex.Unlock()
+		return ErrNotPaired
+	}
+	req, sequence, err := client.prepare(method, request, timeout)
+	client.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// the server still responds to a cancelled call, so the response is
+	// read either way.  Deadlines are already enforced by the server.
+	done := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				_, _ = client.Cancel(sequence)
+			}
+		case <-done:
+		}
+	}()
+	resp, encoded, err := client.post(req)
+	close(done)
+	<-watched
+	if err != nil {
+		return err
+	}
+
+	client.mutex.Lock()
+	body, header, err := client.receive(method, resp, encoded)
+	if err == nil {
+		err = client.verify(method, body, header)
+	}
+	client.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = proto.Unmarshal(body, response)
+	if err != nil {
+		return fmt.Errorf("error decoding response - %w", err)
+	}
+	return responseError(response)
+}
+
+// Cancel asks the server to stop a call that is still in flight, identified
+// by the sequence it was sent with.  It doesn't wait for other calls to
+// finish, so it can be used while the call it cancels is running.  It reports
+// whether the server found the call.
+func (client *Client) Cancel(sequence int32) (bool, error) {
+	request := &messages.CancelRequest{
+		Header:   &messages.RequestHeader{Method: "Cancel"},
+		Sequence: sequence,
+	}
+	response := &messages.CancelResponse{}
+	err := client.Call("Cancel", request, response)
+	if err != nil {
+		return false, err
+	}
+	return response.Cancelled, nil
+}
+
+// send signs & posts a single request, returning the decoded response body.
+// The client must be locked for the whole round trip.
+func (client *Client) send(method string, request proto.Message, timeout time.Duration) ([]byte, http.Header, error) {
+	req, _, err := client.prepare(method, request, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, encoded, err := client.post(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.receive(method, resp, encoded)
+}
+
+// prepare creates a signed request, returning the sequence it was given.
+// The client must be locked.
+func (client *Client) prepare(method string, request proto.Message, timeout time.Duration) (*http.Request, int32, error) {
+	message, err := proto.Marshal(request)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error marshaling request - %w", err)
+	}
+	req, err := client.newRequest(client.Endpoint, message)
+	if err != nil {
+		return nil, 0, err
+	}
+	sequence := client.sign(req, method, message)
+	if timeout > 0 {
+		// round up so a short timeout isn't sent as none at all
+		req.Header.Set("Request-Timeout", strconv.FormatInt(int64((timeout+time.Millisecond-1)/time.Millisecond), 10))
+	}
+	return req, sequence, nil
+}
+
+// post sends a request & reads the whole response body
+func (client *Client) post(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := client.HTTP.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	encoded, err := readBody(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, encoded, nil
+}
+
+// readBody reads a response body, expanding it when the server compressed it
+func readBody(resp *http.Response) ([]byte, error) {
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || encoding == "identity" {
+		return io.ReadAll(resp.Body)
+	}
+	decompressor, err := rpc.Decompressor(encoding, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error opening compressed response - %w", err)
+	}
+	defer decompressor.Close()
+	return io.ReadAll(decompressor)
+}
+
+// receive checks a response & decodes its body.
+// The client must be locked since error responses are verified.
+func (client *Client) receive(method string, resp *http.Response, encoded []byte) ([]byte, http.Header, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, client.transportError(resp, encoded)
+	}
+	if len(encoded) == 0 {
+		return nil, nil, ErrEmptyResponse
+	}
+	if got := resp.Header.Get("Request-Method"); got != method {
+		return nil, nil, fmt.Errorf("%w - expected [%s] but got [%s]", ErrMethodMismatch, method, got)
+	}
+
+	body, err := decodeBody(resp.Header, encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding response body - %w", err)
+	}
+	return body, resp.Header, nil
+}
+
+// newRequest creates a request carrying an encoded message body
+func (client *Client) newRequest(endpoint string, message []byte) (*http.Request, error) {
+	contentType := rpc.ContentTypeHex
+	body := []byte(hex.EncodeToString(message))
+	if client.Binary {
+		contentType = rpc.ContentTypeProtobuf
+		body = message
+	}
+
+	compressed := false
+	if client.Compression != "" && len(body) >= compressThreshold {
+		var err error
+		body, err = rpc.Compress(client.Compression, body)
+		if err != nil {
+			return nil, fmt.Errorf("error compressing request - %w", err)
+		}
+		compressed = true
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if compressed {
+		req.Header.Set("Content-Encoding", client.Compression)
+	}
+	if client.Compression != "" {
+		// asking explicitly stops the HTTP client expanding gzip itself, so
+		// readBody does it instead
+		req.Header.Set("Accept-Encoding", client.Compression)
+	}
+	if client.Binary {
+		req.Header.Set("Accept", rpc.ContentTypeProtobuf)
+	}
+	return req, nil
+}
+
+// decodeBody converts a response body into protobuf bytes.
+// Compressed responses have already been expanded by readBody.
+func decodeBody(header http.Header, body []byte) ([]byte, error) {
+	if strings.HasPrefix(header.Get("Content-Type"), rpc.ContentTypeProtobuf) {
+		return body, nil
+	}
+	return hex.DecodeString(string(body))
+}
+
+// transportError decodes the error a server sends when it rejects a request.
+// Signed error responses are verified & use up their sequence.  Requests the
+// server refuses before authenticating them get unsigned errors & don't use
+// up a sequence on either side.
+func (client *Client) transportError(resp *http.Response, encoded []byte) error {
+	statusErr := fmt.Errorf("unexpected HTTP status - %s", resp.Status)
+	body, err := decodeBody(resp.Header, encoded)
+	if err != nil || len(body) == 0 {
+		return statusErr
+	}
+	if resp.Header.Get("Message-Signature") != "" && len(client.VerifyPublicKey) != 0 {
+		err = client.verify(resp.Header.Get("Request-Method"), body, resp.Header)
+		if err != nil {
+			return err
+		}
+	}
+	response := &messages.EmptyResponse{}
+	err = proto.Unmarshal(body, response)
+	if err != nil {
+		return statusErr
+	}
+	err = responseError(response)
+	if err != nil {
+		return err
+	}
+	return statusErr
+}
+
+// sign advances the send sequence & adds the signed envelope headers to a
+// request, returning the sequence it was given
+func (client *Client) sign(req *http.Request, method string, message []byte) int32 {
+	client.SendCounter++
+	envelope := &rpc.Envelope{
+		Kind:      rpc.EnvelopeRequest,
+		Method:    method,
+		Token:     client.Token,
+		Sequence:  client.SendCounter,
+		Timestamp: time.Now().UnixMilli(),
+		Body:      message,
+	}
+	signature := ed25519.Sign(client.SignPrivateKey, envelope.Bytes())
+
+	req.Header.Set("Request-Method", method)
+	req.Header.Set("Message-Sequence", strconv.FormatInt(int64(envelope.Sequence), 10))
+	req.Header.Set("Message-Timestamp", strconv.FormatInt(envelope.Timestamp, 10))
+	req.Header.Set("Message-Signature", hex.EncodeToString(signature))
+	if client.Token != "" {
+		req.Header.Set("Client-Token", client.Token)
+	}
+	return envelope.Sequence
+}
+
+// verify checks the sequence, timestamp & envelope signature of a response
+func (client *Client) verify(method string, body []byte, header http.Header) error {
+	seq := header.Get("Message-Sequence")
+	if seq == "" {
+		return ErrMissingSequence
+	}
+	sequence, err := strconv.ParseInt(seq, 10, 32)
+	if err != nil {
+		return fmt.Errorf("error decoding response sequence - %w", err)
+	}
+	if !client.RecvWindow.Check(int32(sequence)) {
+		return fmt.Errorf("%w - got [%d] after [%d]", ErrBadSequence, sequence, client.RecvWindow.Highest)
+	}
+
+	timestamp, err := strconv.ParseInt(header.Get("Message-Timestamp"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w - %v", ErrBadTimestamp, err)
+	}
+	skew := time.Since(time.UnixMilli(timestamp))
+	if skew > client.MaxClockSkew || skew < -client.MaxClockSkew {
+		return ErrBadTimestamp
+	}
+
+	sig := header.Get("Message-Signature")
+	if sig == "" {
+		return ErrMissingSignature
+	}
+	signature, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("error decoding response signature - %w", err)
+	}
+	envelope := &rpc.Envelope{
+		Kind:      rpc.EnvelopeResponse,
+		Method:    method,
+		Token:     client.Token,
+		Sequence:  int32(sequence),
+		Timestamp: timestamp,
+		Body:      body,
+	}
+	if !ed25519.Verify(client.VerifyPublicKey, envelope.Bytes(), signature) {
+		return ErrBadSignature
+	}
+	// only a verified response may use up its sequence
+	if !client.RecvWindow.Accept(int32(sequence)) {
+		return fmt.Errorf("%w - got [%d] after [%d]", ErrBadSequence, sequence, client.RecvWindow.Highest)
+	}
+	return nil
+}
+
+// responseError converts a non-OK response header into an error
+func responseError(response proto.Message) error {
+	headered, ok := response.(headeredResponse)
+	if !ok {
+		return nil
+	}
+	err := rpc.HeaderError(headered.GetHeader())
+	if err == nil {
+		return nil
+	}
+	return err
+}
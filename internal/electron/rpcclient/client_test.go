@@ -0,0 +1,335 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpcclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	"github.com/farrcraft/brewtheory/internal/electron/handler"
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+	"github.com/farrcraft/brewtheory/internal/electron/rpcclient"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+// newServer starts an in-process server with the standard handlers & a Slow
+// method that runs until it is cancelled.  The returned channel is signalled
+// each time a Slow call sees it was cancelled.
+func newServer(t *testing.T, secret []byte, middleware ...rpc.Middleware) (*httptest.Server, <-chan struct{}) {
+	t.Helper()
+	logger := logrus.New()
+	logger.Level = logrus.FatalLevel
+	server := rpc.NewServer(logger, make(chan string, 10), make(chan bool, 10))
+	server.Limits.KeyExchange = rpc.RateLimit{}
+	server.Limits.PreAuth = rpc.RateLimit{}
+	if secret != nil {
+		server.Pairing = rpc.NewPairing(logger, secret, false)
+	}
+	server.Use(middleware...)
+	handler.Register(server)
+	cancelled := make(chan struct{}, 1)
+	rpc.Register(server, "Slow", func(context *rpc.RequestContext, request *messages.IdRequest) (*messages.IdResponse, error) {
+		select {
+		case <-context.Context.Done():
+			select {
+			case cancelled <- struct{}{}:
+			default:
+			}
+			return nil, context.Err()
+		case <-time.After(5 * time.Second):
+		}
+		return &messages.IdResponse{Id: request.Id}, nil
+	})
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return ts, cancelled
+}
+
+// newClient creates a client for a test server that has completed a key
+// exchange
+func newClient(t *testing.T, ts *httptest.Server) *rpcclient.Client {
+	t.Helper()
+	client, err := rpcclient.NewClient(ts.URL+"/rpc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.KeyExchange()
+	if err != nil {
+		t.Fatal("key exchange failed - ", err)
+	}
+	return client
+}
+
+// errorCode is the code of an error returned by a call
+func errorCode(err error) codes.Code {
+	return codes.ToInternalError(err).Code
+}
+
+func TestKeyExchange(t *testing.T) {
+	ts, _ := newServer(t, nil)
+	client := newClient(t, ts)
+	if client.Token == "" {
+		t.Error("no session token after key exchange")
+	}
+	if len(client.VerifyPublicKey) == 0 {
+		t.Error("no server public key after key exchange")
+	}
+	if !client.HasCapability("batch") {
+		t.Error("server did not offer the batch capability")
+	}
+}
+
+func TestKeyExchangeBootstrapSecret(t *testing.T) {
+	secret := []byte("0123456789abcdef0123")
+	ts, _ := newServer(t, secret)
+
+	client, _ := rpcclient.NewClient(ts.URL+"/rpc", nil)
+	client.BootstrapSecret = []byte("not the bootstrap secret")
+	err := client.KeyExchange()
+	if errorCode(err) != codes.ErrorBadBootstrapProof {
+		t.Fatal("key exchange with the wrong secret - ", err)
+	}
+
+	client.BootstrapSecret = secret
+	err = client.KeyExchange()
+	if err != nil {
+		t.Fatal("key exchange with the secret - ", err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	// refuse is how the next Rekey call is refused: by an error, which the
+	// server sends with an error status, or by a panic, which Recover turns
+	// into an error header on an OK response
+	var refuse atomic.Value
+	refuse.Store("")
+	refuseRekey := func(next rpc.Handler) rpc.Handler {
+		return func(server *rpc.Server, message []byte, context *rpc.RequestContext) (proto.Message, error) {
+			if context.Header.Method == "Rekey" {
+				switch refuse.Swap("") {
+				case "error":
+					return nil, codes.New(codes.ScopeRPC, codes.ErrorCrypto)
+				case "panic":
+					panic("rekey refused")
+				}
+			}
+			return next(server, message, context)
+		}
+	}
+	ts, _ := newServer(t, nil, rpc.Recover(), refuseRekey)
+	client := newClient(t, ts)
+	verifyKey := client.VerifyPublicKey
+
+	err := client.Rekey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(client.VerifyPublicKey, verifyKey) {
+		t.Error("server key did not change")
+	}
+	err = client.Call("ListMethods", &messages.EmptyRequest{}, &messages.ListMethodsResponse{})
+	if err != nil {
+		t.Fatal("call after rekey - ", err)
+	}
+
+	// a refused rekey must leave the session usable with its current keys
+	for _, refusal := range []string{"error", "panic"} {
+		verifyKey = client.VerifyPublicKey
+		refuse.Store(refusal)
+		err = client.Rekey()
+		if err == nil || !codes.IsInternalError(err) {
+			t.Fatal("expected a refused rekey to return its error but got - ", err)
+		}
+		if !bytes.Equal(client.VerifyPublicKey, verifyKey) {
+			t.Error("refused rekey changed the server key")
+		}
+		err = client.Call("ListMethods", &messages.EmptyRequest{}, &messages.ListMethodsResponse{})
+		if err != nil {
+			t.Fatal("call after a refused rekey - ", err)
+		}
+	}
+}
+
+func TestCallBeforeKeyExchange(t *testing.T) {
+	ts, _ := newServer(t, nil)
+	client, _ := rpcclient.NewClient(ts.URL+"/rpc", nil)
+	err := client.Call("ListMethods", &messages.EmptyRequest{}, &messages.ListMethodsResponse{})
+	if !errors.Is(err, rpcclient.ErrNotPaired) {
+		t.Fatal("expected ErrNotPaired but got - ", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	ts, _ := newServer(t, nil)
+	transports := []struct {
+		name        string
+		binary      bool
+		compression string
+	}{
+		{"hex", false, ""},
+		{"binary", true, ""},
+		{"gzip", true, rpc.EncodingGzip},
+		{"zstd", false, rpc.EncodingZstd},
+	}
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			client := newClient(t, ts)
+			client.Binary = transport.binary
+			client.Compression = transport.compression
+			// enough calls to check the sequences stay in step
+			for i := 0; i < 3; i++ {
+				response := &messages.ListMethodsResponse{}
+				err := client.Call("ListMethods", &messages.EmptyRequest{}, response)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(response.Methods) == 0 {
+					t.Fatal("no methods listed")
+				}
+			}
+			// a large request exercises compressed request bodies
+			request := &messages.IdRequest{Id: strings.Repeat("x", 4096)}
+			response := &messages.IdResponse{}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err := client.CallContext(ctx, "Slow", request, response)
+			if errorCode(err) != codes.ErrorDeadlineExceeded {
+				t.Fatal("expected a deadline error but got - ", err)
+			}
+		})
+	}
+}
+
+func TestCallError(t *testing.T) {
+	ts, _ := newServer(t, nil)
+	client := newClient(t, ts)
+	err := client.Call("NoSuchMethod", &messages.EmptyRequest{}, &messages.EmptyResponse{})
+	if errorCode(err) != codes.ErrorUnknownMethod {
+		t.Fatal("expected an unknown method error but got - ", err)
+	}
+	// errors are signed, so the client must still be in step afterwards
+	err = client.Call("ListMethods", &messages.EmptyRequest{}, &messages.ListMethodsResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ts, _ := newServer(t, nil)
+	client := newClient(t, ts)
+	calls := []*rpcclient.BatchCall{
+		{Method: "ListMethods", Request: &messages.EmptyRequest{}, Response: &messages.ListMethodsResponse{}},
+		{Method: "NoSuchMethod", Request: &messages.EmptyRequest{}, Response: &messages.EmptyResponse{}},
+		{Method: "ListMethods", Request: &messages.EmptyRequest{}, Response: &messages.ListMethodsResponse{}},
+		{Method: "Rekey", Request: &messages.KeyExchangeRequest{}, Response: &messages.KeyExchangeResponse{}},
+	}
+	err := client.Batch(false, calls...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls[0].Err != nil || calls[2].Err != nil {
+		t.Fatal("batch calls failed - ", calls[0].Err, calls[2].Err)
+	}
+	if len(calls[2].Response.(*messages.ListMethodsResponse).Methods) == 0 {
+		t.Error("no methods listed by the batch")
+	}
+	if errorCode(calls[1].Err) != codes.ErrorUnknownMethod {
+		t.Error("expected an unknown method error but got - ", calls[1].Err)
+	}
+	// session only methods must be made on their own
+	if errorCode(calls[3].Err) != codes.ErrorInvalidRequest {
+		t.Error("expected an invalid request error but got - ", calls[3].Err)
+	}
+}
+
+func TestBatchAtomic(t *testing.T) {
+	ts, _ := newServer(t, nil)
+	client := newClient(t, ts)
+	calls := []*rpcclient.BatchCall{
+		{Method: "ListMethods", Request: &messages.EmptyRequest{}, Response: &messages.ListMethodsResponse{}},
+		{Method: "NoSuchMethod", Request: &messages.EmptyRequest{}, Response: &messages.EmptyResponse{}},
+		{Method: "ListMethods", Request: &messages.EmptyRequest{}, Response: &messages.ListMethodsResponse{}},
+	}
+	err := client.Batch(true, calls...)
+	if errorCode(err) != codes.ErrorUnknownMethod {
+		t.Fatal("expected an unknown method error but got - ", err)
+	}
+	if calls[2].Err == nil {
+		t.Error("atomic batch ran a call after the failed one")
+	}
+}
+
+func TestCallContextCancel(t *testing.T) {
+	ts, cancelled := newServer(t, nil)
+	client := newClient(t, ts)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	err := client.CallContext(ctx, "Slow", &messages.IdRequest{}, &messages.IdResponse{})
+	if err == nil {
+		t.Fatal("cancelled call succeeded")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("cancelled call took ", time.Since(start))
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server call was not cancelled")
+	}
+	// the cancel is signed too, so the client must still be in step
+	err = client.Call("ListMethods", &messages.EmptyRequest{}, &messages.ListMethodsResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverlappingCalls(t *testing.T) {
+	ts, _ := newServer(t, nil)
+	client := newClient(t, ts)
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.Call("ListMethods", &messages.EmptyRequest{}, &messages.ListMethodsResponse{})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
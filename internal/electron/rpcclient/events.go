@@ -0,0 +1,163 @@
+/*
+BrewTheory
+Copyright (C) 2022  Joshua Farr
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package rpcclient
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	messages "github.com/farrcraft/brewtheory/internal/electron/proto"
+	"github.com/farrcraft/brewtheory/internal/electron/rpc"
+)
+
+// EventStream is an open subscription to server events
+type EventStream struct {
+	body      io.ReadCloser
+	reader    *bufio.Reader
+	verifyKey ed25519.PublicKey
+	token     string
+	sequence  int32
+}
+
+// Subscribe opens an event stream for the given topics.
+// The subscribe request is signed & sequenced like any other request.
+func (client *Client) Subscribe(topics ...string) (*EventStream, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.Token == "" || len(client.VerifyPublicKey) == 0 {
+		return nil, ErrNotPaired
+	}
+
+	endpoint, err := url.Parse(client.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Path = "/events"
+
+	request := &messages.SubscribeRequest{
+		Header: &messages.RequestHeader{Method: "Subscribe"},
+		Topics: topics,
+	}
+	message, err := proto.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request - %w", err)
+	}
+	req, err := client.newRequest(endpoint.String(), message)
+	if err != nil {
+		return nil, err
+	}
+	client.sign(req, "Subscribe", message)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := client.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// errors are signed responses, so they have to be verified to keep
+		// the sequences in step
+		defer resp.Body.Close()
+		encoded, err := readBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("unexpected event stream content type - %s", resp.Header.Get("Content-Type"))
+		}
+		return nil, client.transportError(resp, encoded)
+	}
+
+	stream := &EventStream{
+		body:      resp.Body,
+		reader:    bufio.NewReader(resp.Body),
+		verifyKey: client.VerifyPublicKey,
+		token:     client.Token,
+	}
+	return stream, nil
+}
+
+// Next blocks until the next event arrives.
+// The event signature & sequence are verified before it is returned.
+// io.EOF is returned when the server closes the stream.
+func (stream *EventStream) Next() (*messages.Event, error) {
+	var data string
+	for {
+		line, err := stream.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if data != "" {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data += strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	encoded, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding event - %w", err)
+	}
+	envelope := &messages.EventEnvelope{}
+	err = proto.Unmarshal(encoded, envelope)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding event envelope - %w", err)
+	}
+	// the signature covers the event's sequence & timestamp, so the event
+	// has to be decoded before it can be verified
+	event := &messages.Event{}
+	err = proto.Unmarshal(envelope.Event, event)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding event - %w", err)
+	}
+	signed := &rpc.Envelope{
+		Kind:      rpc.EnvelopeEvent,
+		Method:    "Subscribe",
+		Token:     stream.token,
+		Sequence:  event.Sequence,
+		Timestamp: event.Timestamp,
+		Body:      envelope.Event,
+	}
+	if !ed25519.Verify(stream.verifyKey, signed.Bytes(), envelope.Signature) {
+		return nil, ErrBadSignature
+	}
+	stream.sequence++
+	if event.Sequence != stream.sequence {
+		return nil, fmt.Errorf("%w - expected [%d] but got [%d]", ErrBadSequence, stream.sequence, event.Sequence)
+	}
+	return event, nil
+}
+
+// Close ends the event stream
+func (stream *EventStream) Close() error {
+	return stream.body.Close()
+}
@@ -19,67 +19,190 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package electron
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	"github.com/farrcraft/brewtheory/internal/electron/codes"
+	"github.com/farrcraft/brewtheory/internal/electron/diag"
 	"github.com/farrcraft/brewtheory/internal/electron/handler"
+	"github.com/farrcraft/brewtheory/internal/electron/jobs"
+	"github.com/farrcraft/brewtheory/internal/electron/logging"
 	"github.com/farrcraft/brewtheory/internal/electron/rpc"
 
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultLogFile is the name of the log file kept in the config directory
+const DefaultLogFile = "brewtheory.log"
+
+// jobsFile is where job history is kept in the config directory
+const jobsFile = "jobs.json"
+
+// DefaultShutdownTimeout is how long in-flight work is given to finish
+// during shutdown before it is forcibly terminated
+const DefaultShutdownTimeout = 10 * time.Second
+
 // Electron is the main service type
 type Electron struct {
-	Logger   *logrus.Logger
-	RPC      *rpc.Server
-	Status   chan string
-	Shutdown chan bool
+	Logger          *logrus.Logger
+	Logs            *logging.Loggers // the root & subsystem loggers
+	LogOptions      logging.Options
+	LogFile         *logging.RotatingFile
+	RPC             *rpc.Server
+	Jobs            *jobs.Manager
+	Status          chan string
+	Shutdown        chan bool
+	ShutdownTimeout time.Duration
+	// BootstrapSecret must be proven by clients during key exchange
+	BootstrapSecret []byte
+	// LockPairing refuses key exchanges after the first one succeeds
+	LockPairing bool
+	// DisableTLS serves plain HTTP on a unix socket listener
+	DisableTLS bool
+	// Limits bound request sizes, timeouts & rates
+	Limits rpc.Limits
+	// JSONGateway serves handlers as JSON to clients holding an API token
+	JSONGateway bool
+	// Address is the address the RPC server listens on
+	Address string
+	// MetricsAddress is the loopback address metrics are served on, metrics
+	// aren't served when it is empty
+	MetricsAddress string
+
+	hooks []shutdownHook
 }
 
-// NewElectron creates a new backend object
-func NewElectron(logLevel string, logFile string) *Electron {
-	backend := &Electron{
-		Logger:   logrus.New(),
-		Status:   make(chan string),
-		Shutdown: make(chan bool),
+// NewElectron creates a new backend object.
+// Logs are written to the log file in the config directory unless another
+// file is given.
+func NewElectron(options logging.Options) (*Electron, error) {
+	path := options.File
+	if path == "" {
+		dir, ok := rpc.ConfigDir(logrus.New())
+		if !ok {
+			return nil, errors.New("no config directory")
+		}
+		path = filepath.Join(dir, "logs", DefaultLogFile)
 	}
-
-	backend.Logger.Formatter = &logrus.JSONFormatter{}
-	level, err := logrus.ParseLevel(logLevel)
+	file, err := logging.OpenRotatingFile(path, options.MaxSize, options.MaxAge, options.MaxBackups)
 	if err != nil {
-		fmt.Println("Invalid log level [", logLevel, "]")
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to open log file [%s] - %w", path, err)
 	}
-	backend.Logger.Level = level
-
-	var file *os.File
-	file, err = os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	logs, err := logging.New(file, options)
 	if err != nil {
-		fmt.Println("Unable to open log file [", logFile, "] - ", err)
-		os.Exit(1)
+		file.Close()
+		return nil, err
 	}
-	backend.Logger.Out = file
 
-	return backend
+	backend := &Electron{
+		Logger:          logs.Root(),
+		Logs:            logs,
+		LogOptions:      options,
+		LogFile:         file,
+		Status:          make(chan string),
+		Shutdown:        make(chan bool, 1),
+		ShutdownTimeout: DefaultShutdownTimeout,
+		Limits:          rpc.DefaultLimits,
+	}
+	return backend, nil
 }
 
-// Run is called when the application is started
-func (service *Electron) Run(servicePort string) {
-	service.RPC = rpc.NewServer(service.Logger, service.Status, service.Shutdown)
-	service.RPC.RegisterHandlers(handler.Handlers())
+// Run is called when the application is started.
+// It blocks until the service has been asked to shut down, either by the
+// frontend, by the RPC server failing, or by SIGINT/SIGTERM.
+func (service *Electron) Run(servicePort string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the log file is closed last, once everything else has shut down
+	service.OnShutdown("logs", func(ctx context.Context) error {
+		return service.Logs.Close()
+	})
+
+	service.Address = servicePort
+	service.RPC = rpc.NewServer(service.Logs.Get("rpc"), service.Status, service.Shutdown)
+	service.RPC.Logs = service.Logs
+	if len(service.BootstrapSecret) == 0 {
+		service.Logger.Warn("No bootstrap secret configured, any local process may pair")
+	}
+	service.RPC.AppVersion = Version
+	service.RPC.DisableTLS = service.DisableTLS
+	service.RPC.Limits = service.Limits
+	service.RPC.Pairing = rpc.NewPairing(service.RPC.Logger, service.BootstrapSecret, service.LockPairing)
+	if service.JSONGateway {
+		tokens, ok := rpc.NewAPITokens(service.Logger)
+		if !ok {
+			fmt.Println(rpc.Failure(codes.New(codes.ScopeGeneral, codes.ErrorLoad)))
+			return errors.New("no config directory")
+		}
+		service.Logger.Info("JSON gateway enabled at [", rpc.GatewayPrefix, "]")
+		service.RPC.APITokens = tokens
+	}
+	if service.MetricsAddress != "" {
+		metrics, err := service.RPC.ServeMetrics(service.MetricsAddress)
+		if err != nil {
+			service.Logger.Error("Error serving metrics - ", err)
+			fmt.Println(rpc.Failure(codes.New(codes.ScopeRPC, codes.ErrorListen)))
+			return err
+		}
+		service.OnShutdown("metrics", metrics.Shutdown)
+	}
+
+	// job history is kept in the config directory so it survives restarts
+	dir, ok := rpc.ConfigDir(service.Logger)
+	if !ok {
+		fmt.Println(rpc.Failure(codes.New(codes.ScopeGeneral, codes.ErrorLoad)))
+		return errors.New("no config directory")
+	}
+	service.Jobs = jobs.NewManager(service.Logs.Get("jobs"), filepath.Join(dir, jobsFile), jobs.DefaultMaxRunning)
+	service.Jobs.OnUpdate = handler.PublishJobs(service.RPC)
+	service.OnShutdown("jobs", service.Jobs.Close)
+	service.RPC.Jobs = service.Jobs
+	service.Jobs.Register(diag.JobName, service.Diagnostics().Runner(filepath.Join(dir, "diagnostics")))
+	service.RPC.Audit, ok = rpc.NewAuditJournal(service.Logger)
+	if !ok {
+		fmt.Println(rpc.Failure(codes.New(codes.ScopeGeneral, codes.ErrorLoad)))
+		return errors.New("no config directory")
+	}
+
+	handler.Register(service.RPC)
+	// audit is outermost so calls that panic are still recorded
+	service.RPC.Use(
+		rpc.Audit(),
+		rpc.Instrument(),
+		rpc.Recover(),
+		rpc.Timing(),
+		rpc.Logging(rpc.DefaultRedactedFields...),
+		rpc.Authorize(handler.Policies()),
+	)
 	go service.RPC.Start(servicePort)
-	for {
+
+	ok = true
+	running := true
+	for running {
 		select {
 		case msg := <-service.Status:
 			fmt.Println(msg)
-		case ok := <-service.Shutdown:
-			service.Logger.Info("Shutting down service...")
-			service.RPC.Stop()
-			if !ok {
-				os.Exit(1)
-			} else {
-				os.Exit(0)
-			}
+		case ok = <-service.Shutdown:
+			running = false
+		case <-ctx.Done():
+			service.Logger.Info("Received shutdown signal")
+			running = false
 		}
 	}
+
+	service.Logger.Info("Shutting down service...")
+	if !service.shutdown() {
+		ok = false
+	}
+	if !ok {
+		return errors.New("service terminated abnormally")
+	}
+	return nil
 }